@@ -0,0 +1,171 @@
+package budget
+
+import (
+	"errors"
+	"github.com/dottics/dutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerPolicy configures the circuit breaker of a Service.
+//
+// The circuit is closed while the budget-micro-service is healthy. Once the
+// rate of failed calls reaches the FailureRate the circuit is opened and
+// the calls fail immediately, without a request to the
+// budget-micro-service, with an error of the kind ErrCircuitOpen. After the
+// OpenTimeout the circuit is half-open and a single call is let through as
+// a probe: if it succeeds the circuit is closed, otherwise the circuit is
+// opened again.
+//
+// A call to GetHome is always let through as a probe, so that the health
+// check also closes the circuit as soon as the budget-micro-service has
+// recovered.
+//
+// A call fails if it returns an error with a status of 500 or higher, for
+// example when the connection is refused or timed out. Calls rejected by
+// the budget-micro-service, such as a 404 response, do not fail the
+// circuit. Calls aborted by their context, because it was cancelled or its
+// deadline passed, are not counted at all.
+type BreakerPolicy struct {
+	// FailureRate is the fraction, between 0 and 1, of failed calls in a
+	// Window which opens the circuit.
+	FailureRate float64
+	// MinCalls is the minimum number of calls in a Window before the
+	// circuit can be opened.
+	MinCalls int
+	// Window is the period over which the failure rate is measured.
+	Window time.Duration
+	// OpenTimeout is the time the circuit stays open before a probe is let
+	// through.
+	OpenTimeout time.Duration
+}
+
+// DefaultBreakerPolicy opens the circuit when half of at least ten calls
+// within ten seconds fail, and probes the budget-micro-service after five
+// seconds.
+var DefaultBreakerPolicy = BreakerPolicy{
+	FailureRate: 0.5,
+	MinCalls:    10,
+	Window:      10 * time.Second,
+	OpenTimeout: 5 * time.Second,
+}
+
+// WithCircuitBreaker adds a circuit breaker configured by the policy p to
+// the Service. By default a Service has no circuit breaker. The views of
+// the Service, see ForUser, share its circuit breaker.
+func WithCircuitBreaker(p BreakerPolicy) Option {
+	return func(s *Service) {
+		s.breaker = &breaker{policy: p, now: time.Now}
+	}
+}
+
+// The states of a circuit breaker.
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// breaker is the circuit breaker of a Service, it is safe for concurrent
+// use. A nil breaker lets all calls through.
+type breaker struct {
+	policy BreakerPolicy
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    int
+	openedAt time.Time
+	// windowStart, calls and failures measure the failure rate while the
+	// circuit is closed.
+	windowStart time.Time
+	calls       int
+	failures    int
+	// probing reports whether a probe is in flight.
+	probing bool
+}
+
+// allow reports whether a call may be made. A call which is let through
+// while the circuit is not closed is a probe, its result decides the state
+// of the circuit. If probe is true the call is let through as a probe even
+// if the circuit is open.
+func (b *breaker) allow(probe bool) (allowed bool, trial bool) {
+	if b == nil {
+		return true, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitOpen && (probe || b.now().Sub(b.openedAt) >= b.policy.OpenTimeout) {
+		b.state = circuitHalfOpen
+	}
+	switch b.state {
+	case circuitOpen:
+		return false, false
+	case circuitHalfOpen:
+		if b.probing && !probe {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	}
+	return true, false
+}
+
+// done records the error e of a call which was let through, trial reports
+// whether the call was a probe and aborted whether the context of the call
+// was cancelled or its deadline passed. An aborted call is not counted, and
+// an aborted probe does not decide the state of the circuit, the next call
+// is let through as a probe instead.
+func (b *breaker) done(trial bool, e dutil.Error, aborted bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	aborted = aborted || errors.Is(e, ErrCanceled)
+	failed := e != nil && dutil.Inst(e).Status >= 500
+	if trial {
+		b.probing = false
+		if aborted {
+			return
+		}
+		if failed {
+			b.open(now)
+		} else {
+			b.state = circuitClosed
+			b.windowStart, b.calls, b.failures = now, 0, 0
+		}
+		return
+	}
+	// the calls which were let through before the circuit was opened do
+	// not change the state of the circuit
+	if aborted || b.state != circuitClosed {
+		return
+	}
+	if now.Sub(b.windowStart) >= b.policy.Window {
+		b.windowStart, b.calls, b.failures = now, 0, 0
+	}
+	b.calls++
+	if failed {
+		b.failures++
+	}
+	if b.calls >= b.policy.MinCalls && float64(b.failures) >= b.policy.FailureRate*float64(b.calls) {
+		b.open(now)
+	}
+}
+
+// open opens the circuit at the time now.
+func (b *breaker) open(now time.Time) {
+	b.state = circuitOpen
+	b.openedAt = now
+}
+
+// circuitErr returns the error of the call identified by id which is not
+// made because the circuit is open.
+func circuitErr(id string) dutil.Error {
+	return newError(id, ErrCircuitOpen, http.StatusServiceUnavailable, "circuit", []string{"the circuit breaker is open"})
+}
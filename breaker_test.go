@@ -0,0 +1,211 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"github.com/dottics/dutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWithCircuitBreaker(t *testing.T) {
+	s := NewService("", WithCircuitBreaker(BreakerPolicy{
+		FailureRate: 0.5,
+		MinCalls:    2,
+		Window:      time.Minute,
+		OpenTimeout: time.Hour,
+	}))
+	var n int32
+	closeServer := statusServer(s, &n, nil, 503, 503, 200)
+	defer closeServer()
+
+	type E struct {
+		requests int32
+		kind     error
+	}
+	tt := []struct {
+		name string
+		call func() dutil.Error
+		E    E
+	}{
+		{
+			name: "first failure",
+			call: func() dutil.Error { _, e := s.GetBudgets(); return e },
+			E:    E{requests: 1, kind: ErrUnavailable},
+		},
+		{
+			name: "second failure opens the circuit",
+			call: func() dutil.Error { _, e := s.GetBudgets(); return e },
+			E:    E{requests: 2, kind: ErrUnavailable},
+		},
+		{
+			name: "open circuit fails fast",
+			call: func() dutil.Error { _, e := s.GetBudgets(); return e },
+			E:    E{requests: 2, kind: ErrCircuitOpen},
+		},
+		{
+			name: "view shares the circuit",
+			call: func() dutil.Error { _, e := s.ForUser("user").GetBudgets(); return e },
+			E:    E{requests: 2, kind: ErrCircuitOpen},
+		},
+		{
+			name: "GetHome probes and closes the circuit",
+			call: func() dutil.Error { _, e := s.GetHome(); return e },
+			E:    E{requests: 3},
+		},
+		{
+			name: "closed circuit",
+			call: func() dutil.Error { _, e := s.GetBudgets(); return e },
+			E:    E{requests: 4},
+		},
+	}
+
+	for i, tc := range tt {
+		e := tc.call()
+		if tc.E.kind == nil && e != nil {
+			t.Errorf("test %v: %v: unexpected error: %v", i, tc.name, e)
+		}
+		if tc.E.kind != nil && !errors.Is(e, tc.E.kind) {
+			t.Errorf("test %v: %v: expected error kind %v got %v", i, tc.name, tc.E.kind, e)
+		}
+		if n != tc.E.requests {
+			t.Errorf("test %v: %v: expected %d requests got %d", i, tc.name, tc.E.requests, n)
+		}
+	}
+
+	e := circuitErr("request-id")
+	if dutil.Inst(e).Status != 503 || e.Error() != "map[circuit:[the circuit breaker is open]]" {
+		t.Errorf("unexpected circuit error: %d %v", dutil.Inst(e).Status, e)
+	}
+}
+
+func TestWithCircuitBreaker_deadline(t *testing.T) {
+	s := NewService("", WithCircuitBreaker(BreakerPolicy{
+		FailureRate: 0.5,
+		MinCalls:    2,
+		Window:      time.Minute,
+		OpenTimeout: time.Hour,
+	}))
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(`{"message":"","data":{"budgets":[]},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	// the calls which time out on the deadline of the caller do not open
+	// the circuit
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		_, e := exchange[struct{}](ctx, s, call{
+			method: "GET",
+			path:   "/budget",
+			query:  url.Values{"slow": {"true"}},
+		})
+		cancel()
+		if e == nil {
+			t.Fatalf("expected error got nil")
+		}
+	}
+	_, e := s.GetBudgets()
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := &breaker{
+		policy: BreakerPolicy{
+			FailureRate: 0.5,
+			MinCalls:    4,
+			Window:      time.Minute,
+			OpenTimeout: 10 * time.Second,
+		},
+		now: func() time.Time { return now },
+	}
+	unavailable := newError("", ErrUnavailable, 503, "request", []string{"refused"})
+	notFound := newError("", ErrNotFound, 404, "budget", []string{"not found"})
+	canceled := newError("", ErrCanceled, StatusCanceled, "canceled", []string{"canceled"})
+	timeout := newError("", ErrTimeout, 504, "timeout", []string{"deadline exceeded"})
+
+	call := func(e dutil.Error) bool {
+		allowed, trial := b.allow(false)
+		if allowed {
+			b.done(trial, e, false)
+		}
+		return allowed
+	}
+
+	// calls aborted by their context are not counted
+	for i := 0; i < 4; i++ {
+		allowed, trial := b.allow(false)
+		if allowed {
+			b.done(trial, timeout, true)
+		}
+	}
+	if b.state != circuitClosed || b.calls != 0 {
+		t.Fatalf("expected the aborted calls not to be counted got %d calls", b.calls)
+	}
+
+	// rejected calls do not fail the circuit
+	for i := 0; i < 4; i++ {
+		call(notFound)
+	}
+	call(unavailable)
+	if b.state != circuitClosed {
+		t.Fatalf("expected the circuit to be closed")
+	}
+
+	// a new window resets the failure rate
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		call(unavailable)
+	}
+	if b.state != circuitClosed {
+		t.Fatalf("expected the circuit to be closed before the minimum calls")
+	}
+	call(nil)
+	if b.state != circuitOpen {
+		t.Fatalf("expected the circuit to be open")
+	}
+	if call(nil) {
+		t.Errorf("expected the call to be rejected while the circuit is open")
+	}
+
+	// half-open lets a single probe through
+	now = now.Add(10 * time.Second)
+	allowed, trial := b.allow(false)
+	if !allowed || !trial {
+		t.Fatalf("expected a probe got allowed %v trial %v", allowed, trial)
+	}
+	if allowed, _ := b.allow(false); allowed {
+		t.Errorf("expected a second probe to be rejected")
+	}
+	b.done(trial, canceled, false)
+	if b.state != circuitHalfOpen {
+		t.Errorf("expected a cancelled probe to keep the circuit half-open")
+	}
+	allowed, trial = b.allow(false)
+	b.done(trial, timeout, true)
+	if !allowed || b.state != circuitHalfOpen {
+		t.Errorf("expected a probe past its deadline to keep the circuit half-open")
+	}
+
+	// a failed probe opens the circuit again
+	allowed, trial = b.allow(false)
+	b.done(trial, unavailable, false)
+	if !allowed || b.state != circuitOpen {
+		t.Errorf("expected a failed probe to open the circuit")
+	}
+
+	// a successful probe closes the circuit
+	allowed, trial = b.allow(true)
+	b.done(trial, nil, false)
+	if !allowed || b.state != circuitClosed {
+		t.Errorf("expected a successful probe to close the circuit")
+	}
+}
@@ -1,76 +1,115 @@
 package budget
 
 import (
+	"context"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
 	"net/url"
 )
 
 func (s *Service) GetBudgets() (Budgets, dutil.Error) {
-	s.URL.Path = "/budget"
+	return s.GetBudgetsContext(context.Background())
+}
 
+// GetBudgetsContext is the same as GetBudgets but uses the context ctx for
+// the requests made to the budget-micro-service.
+func (s *Service) GetBudgetsContext(ctx context.Context) (Budgets, dutil.Error) {
 	type data struct {
 		Budgets Budgets `json:"budgets"`
 	}
+	d, e := exchange[data](ctx, s, call{
+		name:   "GetBudgets",
+		method: "GET",
+		path:   "/budget",
+		tags: func(v interface{}) []string {
+			return []string{"budgets"}
+		},
+	})
+	return d.Budgets, e
+}
 
-	resp := struct {
-		Message string              `json:"message"`
-		Data    data                `json:"data"`
-		Errors  map[string][]string `json:"errors"`
-	}{}
-
-	res, e := s.newRequest("GET", s.URL.String(), nil, nil)
-	if e != nil {
-		return nil, e
-	}
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return nil, e
-	}
+func (s *Service) GetBudget(UUID uuid.UUID) (Budget, dutil.Error) {
+	return s.GetBudgetContext(context.Background(), UUID)
+}
 
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return nil, e
+// GetBudgetContext is the same as GetBudget but uses the context ctx for the
+// requests made to the budget-micro-service.
+func (s *Service) GetBudgetContext(ctx context.Context, UUID uuid.UUID) (Budget, dutil.Error) {
+	type data struct {
+		Budget Budget `json:"budget"`
 	}
-
-	return resp.Data.Budgets, nil
+	d, e := exchange[data](ctx, s, call{
+		name:   "GetBudget",
+		method: "GET",
+		path:   "/budget/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		tags: func(v interface{}) []string {
+			return []string{tag("budget", UUID)}
+		},
+	})
+	return d.Budget, e
 }
 
-func (s *Service) GetBudget(UUID uuid.UUID) (Budget, dutil.Error) {
-	s.URL.Path = "/budget/-"
-	q := url.Values{}
-	q.Add("uuid", UUID.String())
-	s.URL.RawQuery = q.Encode()
+// CreateBudget makes the request to the budget-micro-service to create a new
+// budget.
+func (s *Service) CreateBudget(budget Budget) (Budget, dutil.Error) {
+	return s.CreateBudgetContext(context.Background(), budget)
+}
 
+// CreateBudgetContext is the same as CreateBudget but uses the context ctx
+// for the requests made to the budget-micro-service.
+func (s *Service) CreateBudgetContext(ctx context.Context, budget Budget) (Budget, dutil.Error) {
 	type data struct {
 		Budget Budget `json:"budget"`
 	}
+	d, e := exchange[data](ctx, s, call{
+		name:        "CreateBudget",
+		method:      "POST",
+		path:        "/budget",
+		payload:     budget,
+		invalidates: []string{"budgets"},
+	})
+	return d.Budget, e
+}
 
-	resp := struct {
-		Message string              `json:"message"`
-		Data    data                `json:"data"`
-		Errors  map[string][]string `json:"errors"`
-	}{}
+// UpdateBudget makes the request to the budget-micro-service to update an
+// existing budget, the budget is identified by its UUID. UpdateBudget is
+// also used to rename or deactivate a budget.
+func (s *Service) UpdateBudget(budget Budget) (Budget, dutil.Error) {
+	return s.UpdateBudgetContext(context.Background(), budget)
+}
 
-	res, e := s.newRequest("GET", s.URL.String(), nil, nil)
-	if e != nil {
-		return Budget{}, e
-	}
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return Budget{}, e
+// UpdateBudgetContext is the same as UpdateBudget but uses the context ctx
+// for the requests made to the budget-micro-service.
+func (s *Service) UpdateBudgetContext(ctx context.Context, budget Budget) (Budget, dutil.Error) {
+	type data struct {
+		Budget Budget `json:"budget"`
 	}
+	d, e := exchange[data](ctx, s, call{
+		name:        "UpdateBudget",
+		method:      "PUT",
+		path:        "/budget/-",
+		payload:     budget,
+		invalidates: []string{"budgets", tag("budget", budget.UUID)},
+	})
+	return d.Budget, e
+}
 
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return Budget{}, e
-	}
+// DeleteBudget makes the request to the budget-micro-service to delete the
+// budget identified by the UUID.
+func (s *Service) DeleteBudget(UUID uuid.UUID) dutil.Error {
+	return s.DeleteBudgetContext(context.Background(), UUID)
+}
 
-	return resp.Data.Budget, nil
+// DeleteBudgetContext is the same as DeleteBudget but uses the context ctx
+// for the requests made to the budget-micro-service.
+func (s *Service) DeleteBudgetContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	_, e := exchange[struct{}](ctx, s, call{
+		name:        "DeleteBudget",
+		method:      "DELETE",
+		path:        "/budget/-",
+		query:       url.Values{"uuid": {UUID.String()}},
+		invalidates: []string{"budgets", tag("budget", UUID), tag("groups", UUID)},
+	})
+	return e
 }
@@ -353,4 +353,329 @@ func TestService_GetBudget(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestService_CreateBudget(t *testing.T) {
+	type E struct {
+		budget Budget
+		e      dutil.Error
+	}
+	tt := []struct {
+		name     string
+		budget   Budget
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "403 Permission Required",
+			budget: Budget{
+				Name:   "test budget",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body: `{
+						"message":"Forbidden: unable to process request",
+						"data":{},
+						"errors":{
+							"permission":["Please ensure you have permission"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				budget: Budget{},
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"permission": {"Please ensure you have permission"},
+					},
+				},
+			},
+		},
+		{
+			name: "500 Unmarshal Error",
+			budget: Budget{
+				Name:   "test budget",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"budget created successfully","data":{"budget":{"name":"test budget",}},"errors":{}}`,
+				},
+			},
+			E: E{
+				budget: Budget{},
+				e: &dutil.Err{
+					Status: 500,
+					Errors: map[string][]string{
+						"unmarshal": {"invalid character '}' looking for beginning of object key string"},
+					},
+				},
+			},
+		},
+		{
+			name: "200 Successful",
+			budget: Budget{
+				UserUUID: uuid.MustParse("67b14c0f-b8ea-4f0f-bf07-cadc73cd74d9"),
+				Name:     "test budget",
+				Active:   true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"budget created successfully",
+						"data":{
+							"budget":{
+								"uuid":"f5fca9d0-e308-4ff2-be4e-aff22a4c2a78",
+								"user_uuid":"67b14c0f-b8ea-4f0f-bf07-cadc73cd74d9",
+								"organisation_uuid":null,
+								"name":"test budget",
+								"active":true
+							}
+						},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				budget: Budget{
+					UUID:     uuid.MustParse("f5fca9d0-e308-4ff2-be4e-aff22a4c2a78"),
+					UserUUID: uuid.MustParse("67b14c0f-b8ea-4f0f-bf07-cadc73cd74d9"),
+					Name:     "test budget",
+					Active:   true,
+				},
+				e: nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			budget, e := s.CreateBudget(tc.budget)
+			// test errors
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			// test budget
+			if budget != tc.E.budget {
+				t.Errorf("expected budget '%v' got '%v'", tc.E.budget, budget)
+			}
+			// test exchange request
+			if tc.exchange.Request.Method != "POST" {
+				t.Errorf("expected method '%s' got '%s'", "POST", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != "/budget" {
+				t.Errorf("expected URI '%s' got '%s'", "/budget", tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
+
+func TestService_UpdateBudget(t *testing.T) {
+	type E struct {
+		budget Budget
+		e      dutil.Error
+	}
+	tt := []struct {
+		name     string
+		budget   Budget
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "404 Not Found",
+			budget: Budget{
+				UUID:   uuid.MustParse("f5fca9d0-e308-4ff2-be4e-aff22a4c2a78"),
+				Name:   "renamed budget",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body: `{
+						"message":"NotFound: unable to process request",
+						"data":{},
+						"errors":{
+							"budget":["not found"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				budget: Budget{},
+				e: &dutil.Err{
+					Status: 404,
+					Errors: map[string][]string{
+						"budget": {"not found"},
+					},
+				},
+			},
+		},
+		{
+			name: "200 Deactivated",
+			budget: Budget{
+				UUID:   uuid.MustParse("f5fca9d0-e308-4ff2-be4e-aff22a4c2a78"),
+				Name:   "renamed budget",
+				Active: false,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"budget updated successfully",
+						"data":{
+							"budget":{
+								"uuid":"f5fca9d0-e308-4ff2-be4e-aff22a4c2a78",
+								"user_uuid":"67b14c0f-b8ea-4f0f-bf07-cadc73cd74d9",
+								"organisation_uuid":null,
+								"name":"renamed budget",
+								"active":false
+							}
+						},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				budget: Budget{
+					UUID:     uuid.MustParse("f5fca9d0-e308-4ff2-be4e-aff22a4c2a78"),
+					UserUUID: uuid.MustParse("67b14c0f-b8ea-4f0f-bf07-cadc73cd74d9"),
+					Name:     "renamed budget",
+					Active:   false,
+				},
+				e: nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			budget, e := s.UpdateBudget(tc.budget)
+			// test errors
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			// test budget
+			if budget != tc.E.budget {
+				t.Errorf("expected budget '%v' got '%v'", tc.E.budget, budget)
+			}
+			// test exchange request
+			if tc.exchange.Request.Method != "PUT" {
+				t.Errorf("expected method '%s' got '%s'", "PUT", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != "/budget/-" {
+				t.Errorf("expected URI '%s' got '%s'", "/budget/-", tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
+
+func TestService_DeleteBudget(t *testing.T) {
+	type E struct {
+		e        dutil.Error
+		exReqURI string
+	}
+	tt := []struct {
+		name     string
+		UUID     uuid.UUID
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "403 Permission Required",
+			UUID: uuid.MustParse("7dee09f0-2ba2-4b10-9c88-0c973ad4ebd0"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body: `{
+						"message":"Forbidden: unable to process request",
+						"data":{},
+						"errors":{
+							"permission":["Please ensure you have permission"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/budget/-?uuid=7dee09f0-2ba2-4b10-9c88-0c973ad4ebd0",
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"permission": {"Please ensure you have permission"},
+					},
+				},
+			},
+		},
+		{
+			name: "200 Success",
+			UUID: uuid.MustParse("2c7b7d76-c9e0-49f8-b585-166ac70dba6f"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"budget deleted successfully",
+						"data":{},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/budget/-?uuid=2c7b7d76-c9e0-49f8-b585-166ac70dba6f",
+				e:        nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			e := s.DeleteBudget(tc.UUID)
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			if tc.exchange.Request.Method != "DELETE" {
+				t.Errorf("expected method '%s' got '%s'", "DELETE", tc.exchange.Request.Method)
+			}
+			URI := tc.exchange.Request.RequestURI
+			if URI != tc.E.exReqURI {
+				t.Errorf("expected URI '%s' got '%s'", tc.E.exReqURI, URI)
+			}
+		})
+	}
+}
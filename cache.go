@@ -0,0 +1,203 @@
+package budget
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// The headers of a conditional GET request.
+const (
+	HeaderETag        = "ETag"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// WithCache caches the data of the GET calls made by the Service, and by
+// its views, see ForUser, for the time to live ttl. Once the data has
+// expired it is revalidated with its ETag, if the budget-micro-service sent
+// one, so that the data is only sent again if it has changed. The cache
+// holds at most maxEntries entries, zero or less does not limit the
+// entries. By default a Service does not cache any data.
+//
+// The data is cached for each user, identified by the token the request is
+// made with, so the data of one user is never returned to another user.
+// The data of a request whose token cannot be obtained from its
+// TokenSource is not cached. A successful write invalidates the cached data
+// it changes, for example CreateEvent invalidates the events of its item
+// and CreateGroup invalidates the groups of its budget.
+func WithCache(ttl time.Duration, maxEntries int) Option {
+	return func(s *Service) {
+		s.cache = &cache{
+			ttl:     ttl,
+			max:     maxEntries,
+			now:     time.Now,
+			entries: make(map[string]*cacheEntry),
+			tags:    make(map[string]map[string]bool),
+		}
+	}
+}
+
+// cache caches the data of GET calls, it is safe for concurrent use. Every
+// entry has tags, which identify the resources in the data, so that the
+// entries are invalidated by the writes to those resources. A nil cache
+// caches nothing.
+type cache struct {
+	ttl time.Duration
+	max int
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	// tags holds the keys of the entries of every tag.
+	tags map[string]map[string]bool
+}
+
+// cacheEntry is the cached data of a single GET call.
+type cacheEntry struct {
+	data    json.RawMessage
+	etag    string
+	expires time.Time
+	tags    []string
+}
+
+// requestKey returns the key of the request req made with the context ctx,
+// which identifies the user of the request by a hash of the token the
+// request is made with. The requests with the same key are identical, so
+// they may share their data.
+//
+// A token from a TokenSource is obtained once, the context returned makes
+// the request with the same token. If the token cannot be obtained the user
+// is unknown and requestKey reports false.
+func (s *Service) requestKey(ctx context.Context, req *Request) (context.Context, string, bool) {
+	token := s.Header.Get(HeaderUserToken)
+	if ts := s.tokenSource(ctx); ts != nil {
+		t, e := fetchToken(ctx, req.RequestID, ts, false)
+		if e != nil {
+			return ctx, "", false
+		}
+		token = t
+		ctx = context.WithValue(ctx, tokenSourceKey, resolvedToken{token: t, source: ts})
+	}
+	h := sha256.Sum256([]byte(token))
+	return ctx, hex.EncodeToString(h[:]) + " " + req.Method + " " + req.Path + "?" + req.Query.Encode(), true
+}
+
+// get returns the entry of the key, if any, and reports whether the entry
+// is fresh.
+func (c *cache) get(key string) (*cacheEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return entry, c.now().Before(entry.expires)
+}
+
+// put caches the data, with its etag and tags, as the entry of the key.
+func (c *cache) put(key string, data json.RawMessage, etag string, tags []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	if c.max > 0 && len(c.entries) >= c.max {
+		c.evict()
+	}
+	c.entries[key] = &cacheEntry{
+		data:    data,
+		etag:    etag,
+		expires: c.now().Add(c.ttl),
+		tags:    tags,
+	}
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]bool)
+		}
+		c.tags[tag][key] = true
+	}
+}
+
+// invalidate removes the entries of the tags, for all the users.
+func (c *cache) invalidate(tags ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			c.remove(key)
+		}
+	}
+}
+
+// remove removes the entry of the key, the lock must be held.
+func (c *cache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// evict removes the entry which expires first, the lock must be held.
+func (c *cache) evict() {
+	var first string
+	var expires time.Time
+	for key, entry := range c.entries {
+		if first == "" || entry.expires.Before(expires) {
+			first, expires = key, entry.expires
+		}
+	}
+	c.remove(first)
+}
+
+// tag returns the cache tag of the resource of the kind identified by the
+// UUID, for example the tag "events:<item uuid>" of the events of an item.
+func tag(kind string, UUID uuid.UUID) string {
+	return kind + ":" + UUID.String()
+}
+
+// groupTags returns the cache tags of the groups and all their sub-groups.
+func groupTags(groups Groups) []string {
+	var tags []string
+	for _, g := range groups {
+		tags = append(tags, tag("group", g.UUID))
+		tags = append(tags, groupTags(g.SubGroups)...)
+	}
+	return tags
+}
+
+// itemTags returns the cache tags of the items.
+func itemTags(items Items) []string {
+	tags := make([]string, 0, len(items))
+	for _, i := range items {
+		tags = append(tags, tag("item", i.UUID))
+	}
+	return tags
+}
+
+// eventTags returns the cache tags of the events.
+func eventTags(events Events) []string {
+	tags := make([]string, 0, len(events))
+	for _, ev := range events {
+		tags = append(tags, tag("event", ev.UUID))
+	}
+	return tags
+}
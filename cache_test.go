@@ -0,0 +1,288 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cacheServer responds to the GET requests with the data of their path and
+// the ETag "v1", and with 304 Not Modified to the requests conditional on
+// the ETag. The GET requests received by each request URI are counted.
+type cacheServer struct {
+	mu       sync.Mutex
+	requests map[string]int
+	// conditional is the number of conditional requests received.
+	conditional int
+}
+
+func (c *cacheServer) handler(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r.Method != "GET" {
+		_, _ = w.Write([]byte(`{"message":"","data":{},"errors":{}}`))
+		return
+	}
+	c.requests[r.URL.RequestURI()]++
+	if r.Header.Get(HeaderIfNoneMatch) == `"v1"` {
+		c.conditional++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set(HeaderETag, `"v1"`)
+	data := map[string]string{
+		"/budget":                    `{"budgets":[{"name":"` + r.Header.Get(HeaderUserToken) + `"}]}`,
+		"/budget/-/group":            `{"groups":[{"uuid":"c5a3a3c7-3f4c-4e3c-9d0f-9f0c1f6a5a01","name":"house","sub_groups":[]}]}`,
+		"/budget/group/item/-/event": `{"events":[{"uuid":"8a0f4e6b-5c1d-4b7a-9e2f-3d4c5b6a7e01","name":"rent"}]}`,
+	}[r.URL.Path]
+	_, _ = w.Write([]byte(`{"message":"","data":` + data + `,"errors":{}}`))
+}
+
+func (c *cacheServer) count(uri string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests[uri]
+}
+
+func TestWithCache_revalidate(t *testing.T) {
+	s := NewService("user", WithCache(time.Minute, 0))
+	now := time.Now()
+	s.cache.now = func() time.Time { return now }
+	cs := &cacheServer{requests: make(map[string]int)}
+	srv := mockServer(s, cs.handler)
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		xb, e := s.GetBudgets()
+		if e != nil {
+			t.Fatalf("unexpected error: %v", e)
+		}
+		if len(xb) != 1 || xb[0].Name != "user" {
+			t.Errorf("expected the budgets of '%v' got %v", "user", xb)
+		}
+	}
+	if n := cs.count("/budget"); n != 1 {
+		t.Errorf("expected %d request got %d", 1, n)
+	}
+
+	// an expired entry is revalidated with its ETag
+	now = now.Add(time.Minute)
+	xb, e := s.GetBudgets()
+	if e != nil {
+		t.Fatalf("unexpected error: %v", e)
+	}
+	if len(xb) != 1 || xb[0].Name != "user" {
+		t.Errorf("expected the cached budgets got %v", xb)
+	}
+	if cs.conditional != 1 {
+		t.Errorf("expected %d conditional request got %d", 1, cs.conditional)
+	}
+	// the revalidated entry is fresh again
+	_, _ = s.GetBudgets()
+	if n := cs.count("/budget"); n != 2 {
+		t.Errorf("expected %d requests got %d", 2, n)
+	}
+}
+
+func TestWithCache_users(t *testing.T) {
+	s := NewService("", WithCache(time.Minute, 0))
+	cs := &cacheServer{requests: make(map[string]int)}
+	srv := mockServer(s, cs.handler)
+	defer srv.Close()
+
+	for _, token := range []string{"user-1", "user-2", "user-1"} {
+		xb, e := s.ForUser(token).GetBudgets()
+		if e != nil {
+			t.Fatalf("unexpected error: %v", e)
+		}
+		if len(xb) != 1 || xb[0].Name != token {
+			t.Errorf("expected the budgets of '%v' got %v", token, xb)
+		}
+	}
+	if n := cs.count("/budget"); n != 2 {
+		t.Errorf("expected %d requests got %d", 2, n)
+	}
+}
+
+// userKey is the context key of the user of the test token sources.
+type userKey struct{}
+
+// userTokens is a TokenSource which provides the token of the user of the
+// context, it fails for a context without a user.
+var userTokens = TokenFunc(func(ctx context.Context, refresh bool) (string, error) {
+	user, ok := ctx.Value(userKey{}).(string)
+	if !ok {
+		return "", errors.New("no user")
+	}
+	return user, nil
+})
+
+func TestWithCache_tokenSource(t *testing.T) {
+	s := NewService("", WithCache(time.Minute, 0), WithTokenSource(userTokens))
+	cs := &cacheServer{requests: make(map[string]int)}
+	srv := mockServer(s, cs.handler)
+	defer srv.Close()
+
+	for _, user := range []string{"alice", "bob", "alice"} {
+		ctx := context.WithValue(context.Background(), userKey{}, user)
+		xb, e := s.GetBudgetsContext(ctx)
+		if e != nil {
+			t.Fatalf("unexpected error: %v", e)
+		}
+		if len(xb) != 1 || xb[0].Name != user {
+			t.Errorf("expected the budgets of '%v' got %v", user, xb)
+		}
+	}
+	if n := cs.count("/budget"); n != 2 {
+		t.Errorf("expected %d requests got %d", 2, n)
+	}
+
+	// a request without a token is not answered from the cache
+	_, e := s.GetBudgets()
+	if !errors.Is(e, ErrUnauthorized) {
+		t.Errorf("expected error of kind '%v' got '%v'", ErrUnauthorized, e)
+	}
+}
+
+func TestWithCache_invalidate(t *testing.T) {
+	itemUUID := uuid.New()
+	eventUUID := uuid.MustParse("8a0f4e6b-5c1d-4b7a-9e2f-3d4c5b6a7e01")
+	budgetUUID := uuid.New()
+	otherUUID := uuid.New()
+	events := "/budget/group/item/-/event?uuid=" + itemUUID.String()
+	groups := "/budget/-/group?uuid=" + budgetUUID.String()
+	other := "/budget/-/group?uuid=" + otherUUID.String()
+
+	type E map[string]int
+	tt := []struct {
+		name  string
+		write func(s *Service)
+		E     E
+	}{
+		{
+			name:  "no write",
+			write: func(s *Service) {},
+			E:     E{events: 1, groups: 1, other: 1},
+		},
+		{
+			name: "CreateEvent",
+			write: func(s *Service) {
+				_, _ = s.CreateEvent(itemUUID, Event{})
+			},
+			E: E{events: 2, groups: 1, other: 1},
+		},
+		{
+			name: "UpdateEvent",
+			write: func(s *Service) {
+				_, _ = s.UpdateEvent(Event{UUID: eventUUID})
+			},
+			E: E{events: 2, groups: 1, other: 1},
+		},
+		{
+			name: "DeleteEvent",
+			write: func(s *Service) {
+				_ = s.DeleteEvent(eventUUID)
+			},
+			E: E{events: 2, groups: 1, other: 1},
+		},
+		{
+			name: "DeleteEvent of another item",
+			write: func(s *Service) {
+				_ = s.DeleteEvent(uuid.New())
+			},
+			E: E{events: 1, groups: 1, other: 1},
+		},
+		{
+			name: "CreateGroup",
+			write: func(s *Service) {
+				_, _ = s.CreateGroup(budgetUUID, uuid.Nil, Group{})
+			},
+			E: E{events: 1, groups: 2, other: 1},
+		},
+		{
+			name: "failed write",
+			write: func(s *Service) {
+				s.URL.Host = "127.0.0.1:1"
+				_, _ = s.CreateGroup(budgetUUID, uuid.Nil, Group{})
+			},
+			E: E{events: 1, groups: 1, other: 1},
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewService("", WithCache(time.Minute, 0))
+			cs := &cacheServer{requests: make(map[string]int)}
+			srv := mockServer(s, cs.handler)
+			defer srv.Close()
+
+			get := func() {
+				_, _ = s.GetEvents(itemUUID)
+				_, _ = s.GetGroups(budgetUUID)
+				_, _ = s.GetGroups(otherUUID)
+			}
+			get()
+			host := s.URL.Host
+			tc.write(s)
+			s.URL.Host = host
+			get()
+
+			for uri, n := range tc.E {
+				if cs.count(uri) != n {
+					t.Errorf("test %v: expected %d requests to %v got %d", i, n, uri, cs.count(uri))
+				}
+			}
+		})
+	}
+}
+
+func TestWithCache_copy(t *testing.T) {
+	s := NewService("", WithCache(time.Minute, 0))
+	cs := &cacheServer{requests: make(map[string]int)}
+	srv := mockServer(s, cs.handler)
+	defer srv.Close()
+
+	budgetUUID := uuid.New()
+	xg, _ := s.GetGroups(budgetUUID)
+	xg[0].Name = "modified"
+	xg, _ = s.GetGroups(budgetUUID)
+	if xg[0].Name != "house" {
+		t.Errorf("expected '%v' got '%v'", "house", xg[0].Name)
+	}
+	if n := cs.count("/budget/-/group?uuid=" + budgetUUID.String()); n != 1 {
+		t.Errorf("expected %d request got %d", 1, n)
+	}
+}
+
+func TestCache_evict(t *testing.T) {
+	now := time.Now()
+	c := &cache{
+		ttl:     time.Minute,
+		max:     2,
+		now:     func() time.Time { return now },
+		entries: make(map[string]*cacheEntry),
+		tags:    make(map[string]map[string]bool),
+	}
+	c.put("a", []byte(`"a"`), "", []string{"x"})
+	now = now.Add(time.Second)
+	c.put("b", []byte(`"b"`), "", []string{"x"})
+	c.put("c", []byte(`"c"`), "", []string{"y"})
+
+	if _, ok := c.get("a"); ok {
+		t.Errorf("expected the first entry to be evicted")
+	}
+	c.invalidate("x")
+	if _, ok := c.get("b"); ok {
+		t.Errorf("expected the entry to be invalidated")
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Errorf("expected the entry to be cached")
+	}
+	if len(c.tags) != 1 {
+		t.Errorf("expected %d tag got %d", 1, len(c.tags))
+	}
+}
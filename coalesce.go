@@ -0,0 +1,87 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dottics/dutil"
+	"sync"
+)
+
+// WithCoalescing coalesces the identical GET calls in flight at once, made
+// by the Service and by its views, see ForUser, into a single request to
+// the budget-micro-service. The calls are identical if they are made for
+// the same user, identified by the token the request is made with, to the
+// same endpoint with the same query. A call whose token cannot be obtained
+// from its TokenSource is not coalesced. Every call
+// decodes its own copy of the data, so modifying the data returned to one
+// call does not modify the data returned to the others.
+//
+// The shared request is not cancelled when the context of a call is
+// cancelled, instead the call returns immediately while the request
+// completes for the other calls. The request is still limited by the
+// timeout of the Service.
+func WithCoalescing() Option {
+	return func(s *Service) {
+		s.flights = &flights{m: make(map[string]*flight)}
+	}
+}
+
+// flights are the coalesced requests in flight, by request key.
+type flights struct {
+	mu sync.Mutex
+	m  map[string]*flight
+}
+
+// flight is a single request shared by the identical calls.
+type flight struct {
+	done chan struct{}
+	raw  json.RawMessage
+	etag string
+	e    dutil.Error
+}
+
+// do calls f for the request identified by the key, unless an identical
+// request is already in flight, and returns the result of the request. The
+// call identified by id stops waiting for the result when the context ctx
+// is done.
+func (fs *flights) do(ctx context.Context, key string, id string, f func(ctx context.Context) (json.RawMessage, string, dutil.Error)) (json.RawMessage, string, dutil.Error) {
+	fs.mu.Lock()
+	fl, ok := fs.m[key]
+	if !ok {
+		fl = &flight{done: make(chan struct{})}
+		fs.m[key] = fl
+		go func() {
+			fl.raw, fl.etag, fl.e = f(context.WithoutCancel(ctx))
+			fs.mu.Lock()
+			delete(fs.m, key)
+			fs.mu.Unlock()
+			close(fl.done)
+		}()
+	}
+	fs.mu.Unlock()
+
+	select {
+	case <-fl.done:
+		return fl.raw, fl.etag, copyErr(fl.e)
+	case <-ctx.Done():
+		return nil, "", contextErr(id, ctx.Err())
+	}
+}
+
+// copyErr returns a copy of the error e, so that the error of a shared
+// request is not shared by the calls.
+func copyErr(e dutil.Error) dutil.Error {
+	se, ok := e.(*Error)
+	if !ok || se.Err == nil {
+		return e
+	}
+	c := *se
+	c.Err = &dutil.Err{
+		Status: se.Status,
+		Errors: make(dutil.Errors, len(se.Errors)),
+	}
+	for key, errors := range se.Errors {
+		c.Errors[key] = append([]string(nil), errors...)
+	}
+	return &c
+}
@@ -0,0 +1,296 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// coalesceServer responds to every request with a group once unblock is
+// closed and counts the requests received in n.
+func coalesceServer(s *Service, n *int32, unblock chan struct{}) func() {
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(n, 1)
+		<-unblock
+		_, _ = w.Write([]byte(`{"message":"","data":{"groups":[{"name":"house","sub_groups":[]}]},"errors":{}}`))
+	})
+	return srv.Close
+}
+
+func TestWithCoalescing(t *testing.T) {
+	s := NewService("", WithCoalescing())
+	var n int32
+	unblock := make(chan struct{})
+	closeServer := coalesceServer(s, &n, unblock)
+	defer closeServer()
+
+	budgetUUID := uuid.New()
+	results := make([]Groups, 10)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			xg, e := s.GetGroups(budgetUUID)
+			if e != nil {
+				t.Errorf("unexpected error: %v", e)
+			}
+			results[i] = xg
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+
+	if n != 1 {
+		t.Errorf("expected %d request got %d", 1, n)
+	}
+	results[0][0].Name = "modified"
+	for i, xg := range results[1:] {
+		if len(xg) != 1 || xg[0].Name != "house" {
+			t.Errorf("test %v: expected its own copy of the groups got %v", i+1, xg)
+		}
+	}
+}
+
+func TestWithCoalescing_identical(t *testing.T) {
+	budgetUUID := uuid.New()
+	tt := []struct {
+		name     string
+		call     func(s *Service) dutil.Error
+		requests int32
+	}{
+		{
+			name: "same user",
+			call: func(s *Service) dutil.Error {
+				_, e := s.ForUser("user-1").GetGroups(budgetUUID)
+				return e
+			},
+			requests: 1,
+		},
+		{
+			name: "another user",
+			call: func(s *Service) dutil.Error {
+				_, e := s.ForUser("user-2").GetGroups(budgetUUID)
+				return e
+			},
+			requests: 2,
+		},
+		{
+			name: "another budget",
+			call: func(s *Service) dutil.Error {
+				_, e := s.ForUser("user-1").GetGroups(uuid.New())
+				return e
+			},
+			requests: 2,
+		},
+		{
+			name: "write",
+			call: func(s *Service) dutil.Error {
+				_, e := s.ForUser("user-1").CreateGroup(budgetUUID, uuid.Nil, Group{})
+				return e
+			},
+			requests: 2,
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewService("", WithCoalescing())
+			var n int32
+			unblock := make(chan struct{})
+			closeServer := coalesceServer(s, &n, unblock)
+			defer closeServer()
+
+			errs := make(chan dutil.Error, 2)
+			go func() {
+				_, e := s.ForUser("user-1").GetGroups(budgetUUID)
+				errs <- e
+			}()
+			time.Sleep(20 * time.Millisecond)
+			go func() {
+				errs <- tc.call(s)
+			}()
+			time.Sleep(20 * time.Millisecond)
+			close(unblock)
+
+			for j := 0; j < 2; j++ {
+				if e := <-errs; e != nil {
+					t.Errorf("test %v: unexpected error: %v", i, e)
+				}
+			}
+			if n != tc.requests {
+				t.Errorf("test %v: expected %d requests got %d", i, tc.requests, n)
+			}
+		})
+	}
+}
+
+func TestWithCoalescing_tokenSource(t *testing.T) {
+	s := NewService("", WithCoalescing(), WithTokenSource(userTokens))
+	var n int32
+	unblock := make(chan struct{})
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		<-unblock
+		name := r.Header.Get(HeaderUserToken)
+		_, _ = w.Write([]byte(`{"message":"","data":{"groups":[{"name":"` + name + `","sub_groups":[]}]},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	budgetUUID := uuid.New()
+	users := []string{"alice", "bob"}
+	results := make([]Groups, len(users))
+	wg := sync.WaitGroup{}
+	for i, user := range users {
+		wg.Add(1)
+		go func(i int, user string) {
+			defer wg.Done()
+			ctx := context.WithValue(context.Background(), userKey{}, user)
+			xg, e := s.GetGroupsContext(ctx, budgetUUID)
+			if e != nil {
+				t.Errorf("unexpected error: %v", e)
+			}
+			results[i] = xg
+		}(i, user)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+
+	if n != 2 {
+		t.Errorf("expected %d requests got %d", 2, n)
+	}
+	for i, user := range users {
+		if len(results[i]) != 1 || results[i][0].Name != user {
+			t.Errorf("expected the groups of '%v' got %v", user, results[i])
+		}
+	}
+}
+
+func TestWithCoalescing_tokenRefresh(t *testing.T) {
+	// a coalesced call obtains its token before the request is made, the
+	// token is still refreshed once it is rejected
+	type E struct {
+		requests  int32
+		refreshes int32
+		alive     bool
+		kind      error
+	}
+	tt := []struct {
+		name   string
+		tokens []string
+		E      E
+	}{
+		{
+			name:   "valid token",
+			tokens: []string{"valid"},
+			E:      E{requests: 1, refreshes: 0, alive: true},
+		},
+		{
+			name:   "expired token refreshed",
+			tokens: []string{"expired", "valid"},
+			E:      E{requests: 2, refreshes: 1, alive: true},
+		},
+		{
+			name:   "same token is not resent",
+			tokens: []string{"expired", "expired"},
+			E:      E{requests: 1, refreshes: 1, kind: ErrUnauthorized},
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var refreshes int32
+			tokens := TokenFunc(func(ctx context.Context, refresh bool) (string, error) {
+				if !refresh {
+					return tc.tokens[0], nil
+				}
+				return tc.tokens[atomic.AddInt32(&refreshes, 1)], nil
+			})
+			s := NewService("", WithCoalescing(), WithTokenSource(tokens))
+			var n int32
+			closeServer := tokenServer(s, &n, "valid")
+			defer closeServer()
+
+			alive, e := s.GetHome()
+			if alive != tc.E.alive {
+				t.Errorf("test %v: expected alive %v got %v", i, tc.E.alive, alive)
+			}
+			if tc.E.kind == nil && e != nil {
+				t.Errorf("test %v: expected no error got %v", i, e)
+			}
+			if tc.E.kind != nil && !errors.Is(e, tc.E.kind) {
+				t.Errorf("test %v: expected error kind %v got %v", i, tc.E.kind, e)
+			}
+			if n != tc.E.requests {
+				t.Errorf("test %v: expected %d requests got %d", i, tc.E.requests, n)
+			}
+			if refreshes != tc.E.refreshes {
+				t.Errorf("test %v: expected %d refreshes got %d", i, tc.E.refreshes, refreshes)
+			}
+		})
+	}
+}
+
+func TestWithCoalescing_cancel(t *testing.T) {
+	s := NewService("", WithCoalescing())
+	var n int32
+	unblock := make(chan struct{})
+	closeServer := coalesceServer(s, &n, unblock)
+	defer closeServer()
+
+	budgetUUID := uuid.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan dutil.Error, 1)
+	go func() {
+		_, e := s.GetGroupsContext(ctx, budgetUUID)
+		errs <- e
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan Groups)
+	go func() {
+		xg, e := s.GetGroups(budgetUUID)
+		if e != nil {
+			t.Errorf("unexpected error: %v", e)
+		}
+		done <- xg
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// the first call is cancelled, while the shared request completes for
+	// the second call
+	cancel()
+	if e := <-errs; !errors.Is(e, ErrCanceled) {
+		t.Errorf("expected error kind %v got %v", ErrCanceled, e)
+	}
+	close(unblock)
+	if xg := <-done; len(xg) != 1 {
+		t.Errorf("expected the groups got %v", xg)
+	}
+	if n != 1 {
+		t.Errorf("expected %d request got %d", 1, n)
+	}
+}
+
+func Test_copyErr(t *testing.T) {
+	e := newError("request-id", ErrNotFound, 404, "group", []string{"not found"})
+	c := copyErr(e).(*Error)
+	c.Errors["group"][0] = "modified"
+	if e.Errors["group"][0] != "not found" {
+		t.Errorf("expected '%v' got '%v'", "not found", e.Errors["group"][0])
+	}
+	if c.Kind != ErrNotFound || c.RequestID != "request-id" || c.Status != 404 {
+		t.Errorf("expected a copy of the error got %v", c)
+	}
+	if copyErr(nil) != nil {
+		t.Errorf("expected no error")
+	}
+}
@@ -1,6 +1,7 @@
 package budget
 
 import (
+	"math"
 	"time"
 )
 
@@ -28,25 +29,104 @@ func subtract(x [12]float64, y [12]float64) [12]float64 {
 }
 
 // yearArray converts the event from a time period it and array of monthly amounts.
-// TODO: improve to incorporate specific dates, such as specific date (day) transactions or closest (last friday of month)
+// The amount of every occurrence of the event is added to the month in which
+// it occurs, so that an event which recurs weekly adds its amount four or
+// five times to a month. The amount of an event with a Proration is scaled
+// in the months which the event covers partially.
 func yearArray(year int, e Event) [12]float64 {
 	// declare variable
 	var m [12]float64
 
 	// do computation
-	// loop through months
-	for i := 1; i <= 12; i++ {
-		tmi := time.Month(i)
-		firstOfMonth := time.Date(year, tmi, 1, 0, 0, 0, 0, time.UTC)
-		lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+	// loop through the occurrences of the year
+	firstOfYear := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	lastOfYear := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	for _, o := range e.Occurrences(firstOfYear, lastOfYear) {
+		m[o.Date.Month()-1] += o.Amount
+	}
 
-		afterStart := e.StartDate.Before(lastOfMonth) || e.StartDate.Equal(lastOfMonth)
-		beforeEnd := e.EndDate.After(firstOfMonth) || e.EndDate.Equal(firstOfMonth)
+	return m
+}
 
-		if afterStart && beforeEnd {
-			m[i-1] = e.Amount
+// amountOn returns the amount of the event e on the date d, which is the
+// amount of the last of its amount changes effective on or before the date,
+// or the amount of the event if no change is effective yet. The amount is
+// escalated by the event's Escalation, compounded from the StartDate or from
+// the effective date of the amount change.
+func (e Event) amountOn(d time.Time) float64 {
+	amount, since := e.Amount, date(e.StartDate)
+	var effective time.Time
+	for _, c := range e.AmountChanges {
+		ed := date(c.EffectiveDate)
+		if !ed.After(d) && !ed.Before(effective) {
+			amount, effective, since = c.Amount, ed, ed
 		}
 	}
+	if e.Escalation != nil {
+		amount *= math.Pow(1+e.Escalation.Rate, float64(e.Escalation.escalations(since, d)))
+	}
+	return amount
+}
 
-	return m
+// coverage returns the fraction of the month of the date d which is
+// covered by the event e, by its Proration. An event is prorated only if it
+// has no Recurrence, as a recurring event is counted by its occurrences.
+func (e Event) coverage(d time.Time) float64 {
+	if e.Recurrence != nil || e.Proration == "" {
+		return 1
+	}
+	first := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	start, end := date(e.StartDate), date(e.EndDate)
+	if start.Before(first) {
+		start = first
+	}
+	if end.After(last) {
+		end = last
+	}
+	if end.Before(start) {
+		return 0
+	}
+
+	switch e.Proration {
+	case ProrateCalendarDays:
+		return float64(days(start, end)+1) / float64(last.Day())
+	case Prorate30360:
+		return float64(day30(end)-day30(start)+1) / 30
+	}
+	return 1
+}
+
+// day30 returns the day of the month of the date d by the 30/360
+// convention, in which every month has 30 days.
+func day30(d time.Time) int {
+	if d.Day() == daysIn(d) {
+		return 30
+	}
+	return d.Day()
+}
+
+// escalations returns the number of times an amount from the date since
+// escalates up to and including the date d.
+func (x *Escalation) escalations(since time.Time, d time.Time) int {
+	if d.Before(since) {
+		return 0
+	}
+	if x.Month == 0 {
+		// the anniversaries of since
+		n := d.Year() - since.Year()
+		if d.Before(since.AddDate(n, 0, 0)) {
+			n--
+		}
+		return n
+	}
+	// the first days of the Month after since
+	n := 0
+	for y := since.Year(); y <= d.Year(); y++ {
+		at := time.Date(y, x.Month, 1, 0, 0, 0, 0, time.UTC)
+		if at.After(since) && !at.After(d) {
+			n++
+		}
+	}
+	return n
 }
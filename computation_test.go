@@ -2,6 +2,7 @@ package budget
 
 import (
 	"log"
+	"math"
 	"testing"
 	"time"
 )
@@ -146,3 +147,201 @@ func Test_subtract(t *testing.T) {
 		})
 	}
 }
+
+func TestEvent_amountOn(t *testing.T) {
+	ev := Event{
+		Amount: 1000,
+		AmountChanges: []AmountChange{
+			{EffectiveDate: MustParse("2022-07-01"), Amount: 1200},
+			{EffectiveDate: MustParse("2021-07-01"), Amount: 1100},
+		},
+	}
+	tt := []struct {
+		date   string
+		amount float64
+	}{
+		{"2021-01-01", 1000},
+		{"2021-06-30", 1000},
+		{"2021-07-01", 1100},
+		{"2022-06-30", 1100},
+		{"2022-07-01", 1200},
+		{"2030-01-01", 1200},
+	}
+	for _, tc := range tt {
+		if amount := ev.amountOn(MustParse(tc.date)); amount != tc.amount {
+			t.Errorf("%v: expected %v got %v", tc.date, tc.amount, amount)
+		}
+	}
+}
+
+func Test_yearArray_amountChanges(t *testing.T) {
+	override := 900.0
+	ev := Event{
+		Amount:     1000,
+		StartDate:  MustParse("2021-01-01"),
+		EndDate:    MustParse("2021-12-31"),
+		Recurrence: &Recurrence{Frequency: Monthly},
+		Exceptions: []Exception{{Date: MustParse("2021-08-01"), Amount: &override}},
+		AmountChanges: []AmountChange{
+			{EffectiveDate: MustParse("2021-07-01"), Amount: 1100},
+		},
+	}
+	z := [12]float64{1000, 1000, 1000, 1000, 1000, 1000, 1100, 900, 1100, 1100, 1100, 1100}
+	if x := yearArray(2021, ev); x != z {
+		t.Errorf("expected '%v' got '%v'", z, x)
+	}
+}
+
+func Test_yearArray_escalation(t *testing.T) {
+	rent := Event{
+		Amount:     1000,
+		StartDate:  MustParse("2021-04-01"),
+		EndDate:    MustParse("2026-03-31"),
+		Recurrence: &Recurrence{Frequency: Monthly},
+		Escalation: &Escalation{Rate: 0.06},
+	}
+	// a 5 year projection escalates on every anniversary
+	for year := 2021; year <= 2026; year++ {
+		z := yearArray(year, rent)
+		for i, amount := range z {
+			month := time.Date(year, time.Month(i+1), 1, 0, 0, 0, 0, time.UTC)
+			var x float64
+			if !month.Before(rent.StartDate) && !month.After(rent.EndDate) {
+				years := year - 2021
+				if month.Month() < time.April {
+					years--
+				}
+				x = 1000 * math.Pow(1.06, float64(years))
+			}
+			if math.Abs(amount-x) > 1e-9 {
+				t.Errorf("%v: expected %v got %v", month.Format("2006-01"), x, amount)
+			}
+		}
+	}
+}
+
+func TestEscalation_escalations(t *testing.T) {
+	tt := []struct {
+		name       string
+		escalation Escalation
+		since      string
+		date       string
+		n          int
+	}{
+		{"before the start", Escalation{}, "2021-04-15", "2021-04-01", 0},
+		{"anniversary not reached", Escalation{}, "2021-04-15", "2022-04-14", 0},
+		{"anniversary", Escalation{}, "2021-04-15", "2022-04-15", 1},
+		{"several anniversaries", Escalation{}, "2021-04-15", "2025-05-01", 4},
+		{"leap day anniversary", Escalation{}, "2020-02-29", "2021-03-01", 1},
+		{"fixed month not reached", Escalation{Month: time.March}, "2021-04-15", "2022-02-28", 0},
+		{"fixed month", Escalation{Month: time.March}, "2021-04-15", "2022-03-01", 1},
+		{"fixed month on the start", Escalation{Month: time.March}, "2021-03-01", "2023-03-01", 2},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			n := tc.escalation.escalations(MustParse(tc.since), MustParse(tc.date))
+			if n != tc.n {
+				t.Errorf("expected %d got %d", tc.n, n)
+			}
+		})
+	}
+}
+
+func TestEvent_amountOn_escalation(t *testing.T) {
+	ev := Event{
+		Amount:     100,
+		StartDate:  MustParse("2020-01-01"),
+		EndDate:    MustParse("2030-01-01"),
+		Escalation: &Escalation{Rate: 0.1, Month: time.July},
+		AmountChanges: []AmountChange{
+			{EffectiveDate: MustParse("2022-01-01"), Amount: 200},
+		},
+	}
+	tt := []struct {
+		date   string
+		amount float64
+	}{
+		{"2020-06-30", 100},
+		{"2020-07-01", 110},
+		{"2021-07-01", 121},
+		// the amount change restarts the escalation
+		{"2022-01-01", 200},
+		{"2022-07-01", 220},
+	}
+	for _, tc := range tt {
+		if amount := ev.amountOn(MustParse(tc.date)); math.Abs(amount-tc.amount) > 1e-9 {
+			t.Errorf("%v: expected %v got %v", tc.date, tc.amount, amount)
+		}
+	}
+}
+
+func Test_yearArray_proration(t *testing.T) {
+	tt := []struct {
+		name  string
+		event Event
+		z     [12]float64
+	}{
+		{
+			name: "no proration",
+			event: Event{
+				Amount:    3000,
+				StartDate: MustParse("2021-01-25"),
+				EndDate:   MustParse("2021-03-03"),
+			},
+			z: [12]float64{3000, 3000, 3000, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "calendar days",
+			event: Event{
+				Amount:    3100,
+				StartDate: MustParse("2021-01-25"),
+				EndDate:   MustParse("2021-03-03"),
+				Proration: ProrateCalendarDays,
+			},
+			z: [12]float64{700, 3100, 300, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "30/360",
+			event: Event{
+				Amount:    3000,
+				StartDate: MustParse("2021-01-25"),
+				EndDate:   MustParse("2021-03-03"),
+				Proration: Prorate30360,
+			},
+			z: [12]float64{600, 3000, 300, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "30/360 within a month",
+			event: Event{
+				Amount:    3000,
+				StartDate: MustParse("2021-02-15"),
+				EndDate:   MustParse("2021-02-28"),
+				Proration: Prorate30360,
+			},
+			z: [12]float64{0, 1600, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "recurring event is not prorated",
+			event: Event{
+				Amount:     100,
+				StartDate:  MustParse("2021-01-25"),
+				EndDate:    MustParse("2021-03-03"),
+				Recurrence: &Recurrence{Frequency: Monthly},
+				Proration:  ProrateCalendarDays,
+			},
+			z: [12]float64{100, 100, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			z := yearArray(2021, tc.event)
+			for i := range z {
+				if math.Abs(z[i]-tc.z[i]) > 1e-9 {
+					t.Errorf("expected '%v' got '%v'", tc.z, z)
+					break
+				}
+			}
+		})
+	}
+}
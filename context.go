@@ -0,0 +1,87 @@
+package budget
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+// HeaderRequestID is the header which identifies a request, so that the
+// logs of the Service can be matched with the logs of the
+// budget-micro-service.
+const HeaderRequestID = "X-Request-ID"
+
+// contextKey is the type of the keys of the values the Service reads from a
+// context, so that the keys do not collide with keys of other packages.
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	idempotencyKeyKey
+	userTokenKey
+	tokenSourceKey
+)
+
+// ContextWithRequestID returns a copy of the context ctx with the request ID
+// id. The requests made with the context send id as the X-Request-ID, which
+// is typically the request ID of the API gateway's own request.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID of the context ctx, or an
+// empty string if the context has no request ID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// ContextWithIdempotencyKey returns a copy of the context ctx with the
+// idempotency key. The write requests made with the context send the key as
+// the Idempotency-Key, so that the request can safely be repeated after it
+// failed or timed out without knowing whether the write was performed.
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the context ctx,
+// or an empty string if the context has no idempotency key.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey).(string)
+	return key
+}
+
+// ContextWithUserToken returns a copy of the context ctx with the user's
+// token. The requests made with the context send the token as the
+// X-User-Token, instead of the token of the Service, so that a single
+// Service can make requests on behalf of many users.
+func ContextWithUserToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, userTokenKey, token)
+}
+
+// UserTokenFromContext returns the user's token of the context ctx, and
+// whether the context has a user's token.
+func UserTokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(userTokenKey).(string)
+	return token, ok
+}
+
+// requestID returns the request ID of the context ctx, or a new request ID
+// if the context has none.
+func requestID(ctx context.Context) string {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return id
+	}
+	return uuid.New().String()
+}
+
+// idempotencyHeader returns the headers with the idempotency key of the
+// context ctx, or a new idempotency key if the context has none.
+func idempotencyHeader(ctx context.Context) map[string][]string {
+	key := IdempotencyKeyFromContext(ctx)
+	if key == "" {
+		key = uuid.New().String()
+	}
+	return map[string][]string{
+		HeaderIdempotencyKey: {key},
+	}
+}
@@ -0,0 +1,166 @@
+package budget
+
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"testing"
+)
+
+func TestRequestIDFromContext(t *testing.T) {
+	ctx := context.Background()
+	if id := RequestIDFromContext(ctx); id != "" {
+		t.Errorf("expected '%v' got '%v'", "", id)
+	}
+	ctx = ContextWithRequestID(ctx, "gateway-request-id")
+	if id := RequestIDFromContext(ctx); id != "gateway-request-id" {
+		t.Errorf("expected '%v' got '%v'", "gateway-request-id", id)
+	}
+	if id := requestID(ctx); id != "gateway-request-id" {
+		t.Errorf("expected '%v' got '%v'", "gateway-request-id", id)
+	}
+	if id := requestID(context.Background()); id == "" {
+		t.Errorf("expected a new request id got '%v'", id)
+	}
+}
+
+func TestIdempotencyKeyFromContext(t *testing.T) {
+	ctx := context.Background()
+	if key := IdempotencyKeyFromContext(ctx); key != "" {
+		t.Errorf("expected '%v' got '%v'", "", key)
+	}
+	ctx = ContextWithIdempotencyKey(ctx, "my-key")
+	if key := IdempotencyKeyFromContext(ctx); key != "my-key" {
+		t.Errorf("expected '%v' got '%v'", "my-key", key)
+	}
+	h := idempotencyHeader(ctx)
+	if h[HeaderIdempotencyKey][0] != "my-key" {
+		t.Errorf("expected '%v' got '%v'", "my-key", h[HeaderIdempotencyKey])
+	}
+	h = idempotencyHeader(context.Background())
+	if h[HeaderIdempotencyKey][0] == "" {
+		t.Errorf("expected a new idempotency key got '%v'", h[HeaderIdempotencyKey])
+	}
+}
+
+func TestService_requestHeaders(t *testing.T) {
+	type E struct {
+		requestID      string
+		idempotencyKey string
+	}
+	tt := []struct {
+		name     string
+		ctx      context.Context
+		call     func(s *Service, ctx context.Context) dutil.Error
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "GetEvents with request id",
+			ctx:  ContextWithRequestID(context.Background(), "gateway-request-id"),
+			call: func(s *Service, ctx context.Context) dutil.Error {
+				_, e := s.GetEventsContext(ctx, uuid.New())
+				return e
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"","data":{"events":[]},"errors":{}}`,
+				},
+			},
+			E: E{
+				requestID:      "gateway-request-id",
+				idempotencyKey: "",
+			},
+		},
+		{
+			name: "CreateEvent with idempotency key",
+			ctx: ContextWithIdempotencyKey(
+				ContextWithRequestID(context.Background(), "gateway-request-id"),
+				"create-event-key",
+			),
+			call: func(s *Service, ctx context.Context) dutil.Error {
+				_, e := s.CreateEventContext(ctx, uuid.New(), Event{Name: "event"})
+				return e
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"","data":{"event":{}},"errors":{}}`,
+				},
+			},
+			E: E{
+				requestID:      "gateway-request-id",
+				idempotencyKey: "create-event-key",
+			},
+		},
+		{
+			name: "UpdateEvent generated idempotency key",
+			ctx:  context.Background(),
+			call: func(s *Service, ctx context.Context) dutil.Error {
+				_, e := s.UpdateEventContext(ctx, Event{Name: "event"})
+				return e
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"","data":{"event":{}},"errors":{}}`,
+				},
+			},
+			E: E{
+				requestID:      "*",
+				idempotencyKey: "*",
+			},
+		},
+		{
+			name: "DeleteEvent with idempotency key",
+			ctx:  ContextWithIdempotencyKey(context.Background(), "delete-event-key"),
+			call: func(s *Service, ctx context.Context) dutil.Error {
+				return s.DeleteEventContext(ctx, uuid.New())
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"","data":{},"errors":{}}`,
+				},
+			},
+			E: E{
+				requestID:      "*",
+				idempotencyKey: "delete-event-key",
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	// match compares a header value with the expected value, where "*"
+	// expects any non-empty value
+	match := func(expected string, value string) bool {
+		if expected == "*" {
+			return value != ""
+		}
+		return expected == value
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			e := tc.call(s, tc.ctx)
+			if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			id := tc.exchange.Request.Header.Get(HeaderRequestID)
+			if !match(tc.E.requestID, id) {
+				t.Errorf("expected request id '%v' got '%v'", tc.E.requestID, id)
+			}
+			key := tc.exchange.Request.Header.Get(HeaderIdempotencyKey)
+			if !match(tc.E.idempotencyKey, key) {
+				t.Errorf("expected idempotency key '%v' got '%v'", tc.E.idempotencyKey, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package budget
+
+import (
+	"errors"
+	"github.com/dottics/dutil"
+	"net/http"
+)
+
+// The kinds of errors returned by the Service. The kind of an error is
+// tested with errors.Is, for example errors.Is(e, ErrNotFound), and the
+// Error itself, with its field level Errors, is retrieved with errors.As.
+var (
+	// ErrNotFound is the kind of error of a 404 response.
+	ErrNotFound = errors.New("budget-service: not found")
+	// ErrUnauthorized is the kind of error of a 401 response.
+	ErrUnauthorized = errors.New("budget-service: unauthorized")
+	// ErrForbidden is the kind of error of a 403 response.
+	ErrForbidden = errors.New("budget-service: forbidden")
+	// ErrValidation is the kind of error of a 400 or 422 response.
+	ErrValidation = errors.New("budget-service: validation failed")
+	// ErrConflict is the kind of error of a 409 response.
+	ErrConflict = errors.New("budget-service: conflict")
+	// ErrUnavailable is the kind of error of a 502, 503 or 504 response and
+	// of a request which failed before a response was received.
+	ErrUnavailable = errors.New("budget-service: unavailable")
+	// ErrDecode is the kind of error of a response which could not be read
+	// or decoded.
+	ErrDecode = errors.New("budget-service: decode failed")
+	// ErrCanceled is the kind of error of a request which was aborted
+	// because its context was cancelled.
+	ErrCanceled = errors.New("budget-service: canceled")
+	// ErrTimeout is the kind of error of a request which was aborted because
+	// its context's deadline or the client's timeout was exceeded.
+	ErrTimeout = errors.New("budget-service: timeout")
+	// ErrCircuitOpen is the kind of error of a call which is not made
+	// because the circuit breaker of the Service is open.
+	ErrCircuitOpen = errors.New("budget-service: circuit open")
+)
+
+// Error is the dutil.Error returned by the Service for a failed request. As
+// it embeds the dutil.Err it is used exactly like any other dutil.Error,
+// and it also identifies the request which failed and the kind of error.
+type Error struct {
+	*dutil.Err
+	// Kind is the kind of error, one of the Err variables, or nil if the
+	// error is of no well-known kind, such as a 500 response.
+	Kind error
+	// RequestID is the X-Request-ID of the request which failed.
+	RequestID string
+}
+
+// Unwrap returns the kind of the error and the embedded *dutil.Err, so that
+// errors.Is reports whether the error is of a specific kind and errors.As
+// retrieves the *dutil.Err.
+func (e *Error) Unwrap() []error {
+	var xe []error
+	if e.Kind != nil {
+		xe = append(xe, e.Kind)
+	}
+	if e.Err != nil {
+		xe = append(xe, e.Err)
+	}
+	return xe
+}
+
+// newError returns the Error of the kind for the request identified by id.
+func newError(id string, kind error, status int, key string, errors []string) *Error {
+	return &Error{
+		Err:       dutil.NewErr(status, key, errors),
+		Kind:      kind,
+		RequestID: id,
+	}
+}
+
+// responseErr returns the Error for the response res of the
+// budget-micro-service with the errors of the response body.
+func responseErr(res *http.Response, errors map[string][]string) *Error {
+	return &Error{
+		Err: &dutil.Err{
+			Status: res.StatusCode,
+			Errors: errors,
+		},
+		Kind:      statusKind(res.StatusCode),
+		RequestID: responseRequestID(res),
+	}
+}
+
+// statusKind returns the kind of error of a response with the status.
+func statusKind(status int) error {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrValidation
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// responseRequestID returns the X-Request-ID of the request of the
+// response res.
+func responseRequestID(res *http.Response) string {
+	if res == nil || res.Request == nil {
+		return ""
+	}
+	return res.Request.Header.Get(HeaderRequestID)
+}
@@ -0,0 +1,227 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	var e dutil.Error = newError("my-request-id", ErrNotFound, 404, "budget", []string{"not found"})
+
+	// the error is used as any other dutil.Error
+	if e.Error() != "map[budget:[not found]]" {
+		t.Errorf("expected '%v' got '%v'", "map[budget:[not found]]", e.Error())
+	}
+	ie := dutil.Inst(e)
+	if ie.Status != 404 {
+		t.Errorf("expected status %d got %d", 404, ie.Status)
+	}
+	if e.(*Error).RequestID != "my-request-id" {
+		t.Errorf("expected request id '%v' got '%v'", "my-request-id", e.(*Error).RequestID)
+	}
+}
+
+func TestError_unwrap(t *testing.T) {
+	var e dutil.Error = newError("my-request-id", ErrNotFound, 404, "budget", []string{"not found"})
+
+	if !errors.Is(e, ErrNotFound) {
+		t.Errorf("expected error of kind '%v' got '%v'", ErrNotFound, e)
+	}
+	// the callers which used the *dutil.Err retrieve it with errors.As
+	var de *dutil.Err
+	if !errors.As(e, &de) {
+		t.Fatalf("expected a *dutil.Err got %T", e)
+	}
+	if de.Status != 404 || de.Error() != "map[budget:[not found]]" {
+		t.Errorf("expected '%v' got '%d %v'", "404 map[budget:[not found]]", de.Status, de)
+	}
+
+	// an error of no well-known kind still unwraps to its *dutil.Err
+	e = newError("my-request-id", nil, 500, "internal_server_error", []string{"unexpected"})
+	if !errors.As(e, &de) || de.Status != 500 {
+		t.Errorf("expected a *dutil.Err with status %d got '%v'", 500, e)
+	}
+}
+
+func TestError_requestID(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	ms.Append(&microtest.Exchange{
+		Response: microtest.Response{
+			Status: 403,
+			Body:   `{"message":"","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+		},
+	})
+
+	ctx := ContextWithRequestID(context.Background(), "gateway-request-id")
+	_, e := s.GetBudgetsContext(ctx)
+	if e == nil {
+		t.Fatalf("expected error got nil")
+	}
+	be, ok := e.(*Error)
+	if !ok {
+		t.Fatalf("expected *Error got %T", e)
+	}
+	if be.RequestID != "gateway-request-id" {
+		t.Errorf("expected request id '%v' got '%v'", "gateway-request-id", be.RequestID)
+	}
+	if be.Status != 403 {
+		t.Errorf("expected status %d got %d", 403, be.Status)
+	}
+}
+
+func TestError_kind(t *testing.T) {
+	tt := []struct {
+		name     string
+		status   int
+		body     string
+		kind     error
+		notKinds []error
+	}{
+		{
+			name:     "400 Bad Request",
+			status:   400,
+			body:     `{"message":"","data":{},"errors":{"name":["required"]}}`,
+			kind:     ErrValidation,
+			notKinds: []error{ErrNotFound, ErrConflict},
+		},
+		{
+			name:   "401 Unauthorized",
+			status: 401,
+			body:   `{"message":"","data":{},"errors":{"auth":["token expired"]}}`,
+			kind:   ErrUnauthorized,
+		},
+		{
+			name:     "403 Forbidden",
+			status:   403,
+			body:     `{"message":"","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+			kind:     ErrForbidden,
+			notKinds: []error{ErrUnauthorized},
+		},
+		{
+			name:   "404 Not Found",
+			status: 404,
+			body:   `{"message":"","data":{},"errors":{"budget":["not found"]}}`,
+			kind:   ErrNotFound,
+		},
+		{
+			name:   "409 Conflict",
+			status: 409,
+			body:   `{"message":"","data":{},"errors":{"name":["already exists"]}}`,
+			kind:   ErrConflict,
+		},
+		{
+			name:   "422 Unprocessable Entity",
+			status: 422,
+			body:   `{"message":"","data":{},"errors":{"amount":["must be positive"]}}`,
+			kind:   ErrValidation,
+		},
+		{
+			name:   "503 Service Unavailable",
+			status: 503,
+			body:   `{"message":"","data":{},"errors":{"service":["unavailable"]}}`,
+			kind:   ErrUnavailable,
+		},
+		{
+			name:   "200 Decode Error",
+			status: 200,
+			body:   `{"message":"","data":{"budget":{"name":1}},"errors":{}}`,
+			kind:   ErrDecode,
+		},
+		{
+			name:     "500 Internal Server Error",
+			status:   500,
+			body:     `{"message":"","data":{},"errors":{"internal_server_error":["unexpected"]}}`,
+			kind:     nil,
+			notKinds: []error{ErrUnavailable, ErrDecode},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ms.Append(&microtest.Exchange{
+				Response: microtest.Response{
+					Status: tc.status,
+					Body:   tc.body,
+				},
+			})
+
+			_, e := s.GetBudget(uuid.New())
+			if e == nil {
+				t.Fatalf("expected error got nil")
+			}
+			if tc.kind != nil && !errors.Is(e, tc.kind) {
+				t.Errorf("expected error of kind '%v' got '%v'", tc.kind, e)
+			}
+			for _, kind := range tc.notKinds {
+				if errors.Is(e, kind) {
+					t.Errorf("expected error not of kind '%v'", kind)
+				}
+			}
+			// the field level errors are kept
+			var be *Error
+			if !errors.As(e, &be) {
+				t.Fatalf("expected *Error got %T", e)
+			}
+			if be.Kind != tc.kind {
+				t.Errorf("expected kind '%v' got '%v'", tc.kind, be.Kind)
+			}
+			if len(be.Errors) != 1 {
+				t.Errorf("expected 1 field error got '%v'", be.Errors)
+			}
+			if dutil.Inst(e).Status != be.Status {
+				t.Errorf("expected status %d got %d", be.Status, dutil.Inst(e).Status)
+			}
+		})
+	}
+}
+
+func TestError_notJSON(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	ms.Append(&microtest.Exchange{
+		Response: microtest.Response{
+			Status: 502,
+			Body:   `<html><body><h1>502 Bad Gateway</h1></body></html>`,
+		},
+	})
+
+	_, e := s.GetBudget(uuid.New())
+	if e == nil {
+		t.Fatalf("expected error got nil")
+	}
+	if !errors.Is(e, ErrUnavailable) {
+		t.Errorf("expected error of kind '%v' got '%v'", ErrUnavailable, e)
+	}
+	if errors.Is(e, ErrDecode) {
+		t.Errorf("expected error not of kind '%v'", ErrDecode)
+	}
+	if dutil.Inst(e).Status != 502 {
+		t.Errorf("expected status %d got %d", 502, dutil.Inst(e).Status)
+	}
+}
+
+func TestError_contextKind(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, e := s.GetBudgetsContext(ctx)
+	if !errors.Is(e, ErrCanceled) {
+		t.Errorf("expected error of kind '%v' got '%v'", ErrCanceled, e)
+	}
+}
@@ -1,6 +1,7 @@
 package budget
 
 import (
+	"context"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
 	"net/url"
@@ -9,46 +10,38 @@ import (
 // GetEvents retrieves all the events from the budget-micro-service that are
 // related to an item.
 func (s *Service) GetEvents(UUID uuid.UUID) (Events, dutil.Error) {
-	s.URL.Path = "/budget/group/item/-/event"
-	q := url.Values{
-		"uuid": {UUID.String()},
-	}
-	s.URL.RawQuery = q.Encode()
+	return s.GetEventsContext(context.Background(), UUID)
+}
 
+// GetEventsContext is the same as GetEvents but uses the context ctx for the
+// requests made to the budget-micro-service.
+func (s *Service) GetEventsContext(ctx context.Context, UUID uuid.UUID) (Events, dutil.Error) {
 	type data struct {
 		Events Events `json:"events"`
 	}
-	resp := struct {
-		Message string              `json:"message"`
-		Data    data                `json:"data"`
-		Errors  map[string][]string `json:"errors"`
-	}{}
-
-	res, e := s.newRequest("GET", s.URL.String(), nil, nil)
-	if e != nil {
-		return nil, e
-	}
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return nil, e
-	}
-
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return nil, e
-	}
-
-	return resp.Data.Events, nil
+	d, e := exchange[data](ctx, s, call{
+		name:   "GetEvents",
+		method: "GET",
+		path:   "/budget/group/item/-/event",
+		query:  url.Values{"uuid": {UUID.String()}},
+		tags: func(v interface{}) []string {
+			return append(eventTags(v.(data).Events), tag("events", UUID))
+		},
+	})
+	return d.Events, e
 }
 
 // CreateEvent makes the request to the budget-micro-service to create a new
 // event and associate that event with an item
 func (s *Service) CreateEvent(UUID uuid.UUID, event Event) (Event, dutil.Error) {
-	s.URL.Path = "/event"
+	return s.CreateEventContext(context.Background(), UUID, event)
+}
 
+// CreateEventContext is the same as CreateEvent but uses the context ctx for
+// the requests made to the budget-micro-service. The request is sent with the
+// idempotency key of the context, see ContextWithIdempotencyKey, or with a
+// new idempotency key.
+func (s *Service) CreateEventContext(ctx context.Context, UUID uuid.UUID, event Event) (Event, dutil.Error) {
 	p := struct {
 		ItemUUID uuid.UUID `json:"item_uuid"`
 		Event    Event     `json:"event"`
@@ -57,104 +50,59 @@ func (s *Service) CreateEvent(UUID uuid.UUID, event Event) (Event, dutil.Error)
 		Event:    event,
 	}
 
-	payload, e := dutil.MarshalReader(p)
-	if e != nil {
-		return Event{}, e
-	}
-	res, e := s.newRequest("POST", s.URL.String(), nil, payload)
-	if e != nil {
-		return Event{}, e
-	}
-
 	type data struct {
 		Event Event `json:"event"`
 	}
-	resp := struct {
-		Message string              `json:"message"`
-		Data    data                `json:"data"`
-		Errors  map[string][]string `json:"errors"`
-	}{}
-
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return Event{}, nil
-	}
-
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return Event{}, e
-	}
-
-	return resp.Data.Event, nil
+	d, e := exchange[data](ctx, s, call{
+		name:        "CreateEvent",
+		method:      "POST",
+		path:        "/event",
+		headers:     idempotencyHeader(ctx),
+		payload:     p,
+		invalidates: []string{tag("events", UUID)},
+	})
+	return d.Event, e
 }
 
 func (s *Service) UpdateEvent(event Event) (Event, dutil.Error) {
-	s.URL.Path = "/event/-"
-
-	payload, e := dutil.MarshalReader(event)
-	if e != nil {
-		return Event{}, e
-	}
-	res, e := s.newRequest("PUT", s.URL.String(), nil, payload)
-	if e != nil {
-		return Event{}, nil
-	}
+	return s.UpdateEventContext(context.Background(), event)
+}
 
+// UpdateEventContext is the same as UpdateEvent but uses the context ctx for
+// the requests made to the budget-micro-service. The request is sent with the
+// idempotency key of the context, see ContextWithIdempotencyKey, or with a
+// new idempotency key.
+func (s *Service) UpdateEventContext(ctx context.Context, event Event) (Event, dutil.Error) {
 	type data struct {
 		Event Event `json:"event"`
 	}
-	resp := struct {
-		Message string              `json:"message"`
-		Data    data                `json:"data"`
-		Errors  map[string][]string `json:"errors"`
-	}{}
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return Event{}, e
-	}
-
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return Event{}, e
-	}
-
-	return resp.Data.Event, nil
+	d, e := exchange[data](ctx, s, call{
+		name:        "UpdateEvent",
+		method:      "PUT",
+		path:        "/event/-",
+		headers:     idempotencyHeader(ctx),
+		payload:     event,
+		invalidates: []string{tag("event", event.UUID)},
+	})
+	return d.Event, e
 }
 
 func (s *Service) DeleteEvent(UUID uuid.UUID) dutil.Error {
-	s.URL.Path = "/event/-"
-	q := url.Values{
-		"uuid": {UUID.String()},
-	}
-	s.URL.RawQuery = q.Encode()
-
-	res, e := s.newRequest("DELETE", s.URL.String(), nil, nil)
-	if e != nil {
-		return e
-	}
-
-	resp := struct {
-		Message string              `json:"message"`
-		Data    map[string]string   `json:"data"`
-		Errors  map[string][]string `json:"errors"`
-	}{}
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return e
-	}
+	return s.DeleteEventContext(context.Background(), UUID)
+}
 
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return e
-	}
-	return nil
+// DeleteEventContext is the same as DeleteEvent but uses the context ctx for
+// the requests made to the budget-micro-service. The request is sent with the
+// idempotency key of the context, see ContextWithIdempotencyKey, or with a
+// new idempotency key.
+func (s *Service) DeleteEventContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	_, e := exchange[struct{}](ctx, s, call{
+		name:        "DeleteEvent",
+		method:      "DELETE",
+		path:        "/event/-",
+		query:       url.Values{"uuid": {UUID.String()}},
+		headers:     idempotencyHeader(ctx),
+		invalidates: []string{tag("event", UUID)},
+	})
+	return e
 }
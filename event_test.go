@@ -4,6 +4,7 @@ import (
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
 	"github.com/johannesscr/micro/microtest"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -352,7 +353,7 @@ func TestService_CreateEvent(t *testing.T) {
 			}
 
 			// test event
-			if ev != tc.E.event {
+			if !reflect.DeepEqual(ev, tc.E.event) {
 				t.Errorf("expected an event '%v' got '%v'", tc.E.event, ev)
 			}
 
@@ -473,7 +474,7 @@ func TestUpdateEvent(t *testing.T) {
 				t.Errorf("unexpected error: %s", e.Error())
 			}
 			// test event
-			if tc.E.event != ev {
+			if !reflect.DeepEqual(tc.E.event, ev) {
 				t.Errorf("expected event '%v' got '%v'", tc.E.event, ev)
 			}
 		})
@@ -0,0 +1,219 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dottics/dutil"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// envelope is the body of every response from the budget-micro-service.
+// The data is decoded separately, once the status of the response is known
+// to be successful.
+type envelope struct {
+	Message string              `json:"message"`
+	Data    json.RawMessage     `json:"data"`
+	Errors  map[string][]string `json:"errors"`
+}
+
+// call describes a single request to the budget-micro-service.
+type call struct {
+	// name is the name of the Service method which makes the call.
+	name   string
+	method string
+	path   string
+	query  url.Values
+	// headers are the additional headers of the request.
+	headers map[string][]string
+	// payload is marshalled as the JSON body of the request if it is not
+	// nil.
+	payload interface{}
+	// tags returns the cache tags of the decoded data v of a GET call. The
+	// data of a call without tags is not cached.
+	tags func(v interface{}) []string
+	// invalidates are the cache tags invalidated by a successful write.
+	invalidates []string
+}
+
+// exchange makes the request described by the call c to the
+// budget-micro-service, through the service's interceptors, and decodes the
+// response envelope. The result of the call is observed by the service's
+// Collector. The data of a successful response is returned, for any
+// other response the errors of the envelope are returned as an Error.
+func exchange[T any](ctx context.Context, s *Service, c call) (T, dutil.Error) {
+	// every interceptor and every attempt of the request share the same
+	// request ID
+	id := requestID(ctx)
+	ctx = ContextWithRequestID(ctx, id)
+	req := &Request{
+		Endpoint:  c.name,
+		Method:    c.method,
+		Path:      c.path,
+		Query:     c.query,
+		Header:    make(http.Header),
+		Payload:   c.payload,
+		RequestID: id,
+	}
+	for key, values := range c.headers {
+		req.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+
+	start := time.Now()
+	res := s.intercept(ctx, req, roundTrip[T](s, c))
+	s.observe(req, res, time.Since(start))
+	data, _ := res.Data.(T)
+	return data, res.Err
+}
+
+// roundTrip returns the Handler which makes the request of the call c to
+// the budget-micro-service and decodes the data of the response as a T.
+//
+// The data of a GET call with cache tags is cached by the service's cache
+// and a successful write invalidates the cache tags of the call. Identical
+// GET calls in flight at once share a single request if the service
+// coalesces requests, every call decodes its own copy of the data.
+func roundTrip[T any](s *Service, c call) Handler {
+	return func(ctx context.Context, req *Request) *Result {
+		var key string
+		var entry *cacheEntry
+		cached := s.cache != nil && req.Method == "GET" && c.tags != nil
+		coalesced := s.flights != nil && req.Method == "GET"
+		if cached || coalesced {
+			var ok bool
+			ctx, key, ok = s.requestKey(ctx, req)
+			// the request of an unknown user does not share its data
+			cached, coalesced = cached && ok, coalesced && ok
+		}
+		if cached {
+			var fresh bool
+			entry, fresh = s.cache.get(key)
+			if fresh {
+				return result(unmarshalData[T](req.RequestID, entry.data))
+			}
+		}
+
+		var raw json.RawMessage
+		var etag string
+		var e dutil.Error
+		if coalesced {
+			raw, etag, e = s.flights.do(ctx, key, req.RequestID, func(ctx context.Context) (json.RawMessage, string, dutil.Error) {
+				return send(ctx, s, req, entry)
+			})
+		} else {
+			raw, etag, e = send(ctx, s, req, entry)
+		}
+		if e != nil {
+			var zero T
+			return result(zero, e)
+		}
+		data, e := unmarshalData[T](req.RequestID, raw)
+		if e != nil {
+			return result(data, e)
+		}
+
+		if cached {
+			s.cache.put(key, raw, etag, c.tags(data))
+		}
+		if req.Method != "GET" {
+			s.cache.invalidate(c.invalidates...)
+		}
+		return result(data, nil)
+	}
+}
+
+// send makes the request req to the budget-micro-service, see fetch, once
+// the request is within the service's limits. The request is not made
+// while the service's circuit breaker is open.
+func send(ctx context.Context, s *Service, req *Request, entry *cacheEntry) (json.RawMessage, string, dutil.Error) {
+	release, err := s.limits.wait(ctx, req.Endpoint)
+	if err != nil {
+		return nil, "", contextErr(req.RequestID, err)
+	}
+	defer release()
+
+	allowed, trial := s.breaker.allow(req.Endpoint == "GetHome")
+	if !allowed {
+		return nil, "", circuitErr(req.RequestID)
+	}
+	raw, etag, e := fetch(ctx, s, req, entry)
+	s.breaker.done(trial, e, ctx.Err() != nil)
+	return raw, etag, e
+}
+
+// result returns the Result of the data, or of the error e.
+func result[T any](data T, e dutil.Error) *Result {
+	if e != nil {
+		return &Result{Status: dutil.Inst(e).Status, Err: e}
+	}
+	return &Result{Status: http.StatusOK, Data: data}
+}
+
+// fetch makes the request req to the budget-micro-service, decodes the
+// response envelope and returns the data of the envelope with the ETag of
+// the response.
+//
+// If the cache entry is not nil the request is conditional on the ETag of
+// the entry, and the data of the entry is returned if the data has not
+// been modified.
+func fetch(ctx context.Context, s *Service, req *Request, entry *cacheEntry) (json.RawMessage, string, dutil.Error) {
+	var payload io.Reader
+	if req.Payload != nil {
+		p, e := dutil.MarshalReader(req.Payload)
+		if e != nil {
+			return nil, "", e
+		}
+		payload = p
+	}
+
+	headers := req.Header
+	if entry != nil && entry.etag != "" {
+		headers = req.Header.Clone()
+		headers.Set(HeaderIfNoneMatch, entry.etag)
+	}
+	res, e := s.newRequest(ctx, req.Method, s.endpoint(req.Path, req.Query), headers, payload)
+	if e != nil {
+		return nil, "", e
+	}
+	if res.StatusCode == http.StatusNotModified && entry != nil {
+		discard(res)
+		return entry.data, entry.etag, nil
+	}
+	resp := envelope{}
+	_, e = s.decode(res, &resp)
+	// a failed response, such as a gateway's HTML error page, keeps its
+	// status and kind even if it has no envelope
+	if e != nil && res.StatusCode != 200 && errors.Is(e, ErrDecode) {
+		return nil, "", responseErr(res, dutil.Inst(e).Errors)
+	}
+	if e != nil {
+		return nil, "", e
+	}
+
+	if res.StatusCode != 200 {
+		return nil, "", responseErr(res, resp.Errors)
+	}
+	return resp.Data, res.Header.Get(HeaderETag), nil
+}
+
+// unmarshalData decodes the data of the envelope of the request identified
+// by id as a T. The data of a call which discards it, with T struct{}, is
+// not decoded, so that any data is accepted.
+func unmarshalData[T any](id string, raw json.RawMessage) (T, dutil.Error) {
+	var data T
+	if _, discarded := any(data).(struct{}); discarded {
+		return data, nil
+	}
+	if len(raw) > 0 {
+		err := json.Unmarshal(raw, &data)
+		if err != nil {
+			var zero T
+			e := newError(id, ErrDecode, 500, "unmarshal", []string{err.Error()})
+			return zero, e
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,162 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"net"
+	"testing"
+)
+
+func Test_exchange(t *testing.T) {
+	type data struct {
+		Name string `json:"name"`
+	}
+	type E struct {
+		data data
+		e    dutil.Error
+	}
+	tt := []struct {
+		name     string
+		call     call
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "marshal error",
+			call: call{
+				method:  "POST",
+				path:    "/",
+				payload: make(chan int),
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 500,
+					Errors: map[string][]string{
+						"marshal": {"json: unsupported type: chan int"},
+					},
+				},
+			},
+		},
+		{
+			name: "unmarshal error",
+			call: call{
+				method: "GET",
+				path:   "/",
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"","data":{"name":1},"errors":{}}`,
+				},
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 500,
+					Errors: map[string][]string{
+						"unmarshal": {"json: cannot unmarshal number into Go struct field data.name of type string"},
+					},
+				},
+			},
+		},
+		{
+			name: "404 Not Found",
+			call: call{
+				method: "GET",
+				path:   "/",
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"","data":{},"errors":{"name":["not found"]}}`,
+				},
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 404,
+					Errors: map[string][]string{
+						"name": {"not found"},
+					},
+				},
+			},
+		},
+		{
+			name: "200 Successful",
+			call: call{
+				method:  "PUT",
+				path:    "/",
+				payload: data{Name: "james"},
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"","data":{"name":"james"},"errors":{}}`,
+				},
+			},
+			E: E{
+				data: data{Name: "james"},
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.exchange != nil {
+				ms.Append(tc.exchange)
+			}
+
+			d, e := exchange[data](context.Background(), s, tc.call)
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+				if e != nil && dutil.Inst(e).Status != dutil.Inst(tc.E.e).Status {
+					t.Errorf("expected status %d got %d", dutil.Inst(tc.E.e).Status, dutil.Inst(e).Status)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			if d != tc.E.data {
+				t.Errorf("expected data '%v' got '%v'", tc.E.data, d)
+			}
+		})
+	}
+}
+
+// Test_exchange_errors tests that the endpoints, which previously returned
+// a nil error when the request failed, return the error.
+func Test_exchange_errors(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	ms.Append(&microtest.Exchange{
+		Response: microtest.Response{
+			Status: 200,
+			Body:   `{"message":"","data":{"event":{"name":1}},"errors":{}}`,
+		},
+	})
+
+	_, e := s.CreateEvent(uuid.New(), Event{})
+	if !errors.Is(e, ErrDecode) {
+		t.Errorf("expected error of kind '%v' got '%v'", ErrDecode, e)
+	}
+	ms.Server.Close()
+
+	// find an address on which nothing is listening
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.SetURL("http", l.Addr().String())
+	_ = l.Close()
+
+	_, e = s.UpdateEvent(Event{})
+	if !errors.Is(e, ErrUnavailable) {
+		t.Errorf("expected error of kind '%v' got '%v'", ErrUnavailable, e)
+	}
+}
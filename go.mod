@@ -1,6 +1,6 @@
 module github.com/dottics/budgetserv
 
-go 1.17
+go 1.21
 
 require (
 	github.com/dottics/dutil v0.0.0-20211102062956-544d4946a1a4
@@ -1,6 +1,7 @@
 package budget
 
 import (
+	"context"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
 	"net/url"
@@ -10,39 +11,138 @@ import (
 // is identified using the uuid parameter.
 //
 // uuid (uuid.UUID) for the budget.
-func (s * Service) GetGroups(UUID uuid.UUID) (Groups, dutil.Error) {
-	s.URL.Path = "/budget/-/group"
+func (s *Service) GetGroups(UUID uuid.UUID) (Groups, dutil.Error) {
+	return s.GetGroupsContext(context.Background(), UUID)
+}
+
+// GetGroupsContext is the same as GetGroups but uses the context ctx for the
+// requests made to the budget-micro-service.
+func (s *Service) GetGroupsContext(ctx context.Context, UUID uuid.UUID) (Groups, dutil.Error) {
+	type data struct {
+		Groups Groups `json:"groups"`
+	}
 	// uuid denotes the budget's uuid
-	q := url.Values{
-		"uuid": {UUID.String()},
+	d, e := exchange[data](ctx, s, call{
+		name:   "GetGroups",
+		method: "GET",
+		path:   "/budget/-/group",
+		query:  url.Values{"uuid": {UUID.String()}},
+		tags: func(v interface{}) []string {
+			return append(groupTags(v.(data).Groups), tag("groups", UUID))
+		},
+	})
+	return d.Groups, e
+}
+
+// CreateGroup makes the request to the budget-micro-service to create a new
+// group in the budget identified by budgetUUID. If parentUUID is not
+// uuid.Nil then the group is created as a sub-group of the parent group.
+func (s *Service) CreateGroup(budgetUUID uuid.UUID, parentUUID uuid.UUID, group Group) (Group, dutil.Error) {
+	return s.CreateGroupContext(context.Background(), budgetUUID, parentUUID, group)
+}
+
+// CreateGroupContext is the same as CreateGroup but uses the context ctx for
+// the requests made to the budget-micro-service.
+func (s *Service) CreateGroupContext(ctx context.Context, budgetUUID uuid.UUID, parentUUID uuid.UUID, group Group) (Group, dutil.Error) {
+	p := struct {
+		BudgetUUID uuid.UUID  `json:"budget_uuid"`
+		ParentUUID *uuid.UUID `json:"parent_uuid,omitempty"`
+		Group      Group      `json:"group"`
+	}{
+		BudgetUUID: budgetUUID,
+		Group:      group,
+	}
+	if parentUUID != uuid.Nil {
+		p.ParentUUID = &parentUUID
 	}
-	s.URL.RawQuery = q.Encode()
 
 	type data struct {
-		Groups Groups `json:"groups"`
+		Group Group `json:"group"`
+	}
+	d, e := exchange[data](ctx, s, call{
+		name:        "CreateGroup",
+		method:      "POST",
+		path:        "/group",
+		payload:     p,
+		invalidates: []string{tag("groups", budgetUUID)},
+	})
+	return d.Group, e
+}
+
+// UpdateGroup makes the request to the budget-micro-service to update an
+// existing group, the group is identified by its UUID. Only the group itself
+// is updated, the sub-groups are left as they are.
+func (s *Service) UpdateGroup(group Group) (Group, dutil.Error) {
+	return s.UpdateGroupContext(context.Background(), group)
+}
+
+// UpdateGroupContext is the same as UpdateGroup but uses the context ctx for
+// the requests made to the budget-micro-service.
+func (s *Service) UpdateGroupContext(ctx context.Context, group Group) (Group, dutil.Error) {
+	type data struct {
+		Group Group `json:"group"`
 	}
+	d, e := exchange[data](ctx, s, call{
+		name:        "UpdateGroup",
+		method:      "PUT",
+		path:        "/group/-",
+		payload:     group,
+		invalidates: []string{tag("group", group.UUID)},
+	})
+	return d.Group, e
+}
 
-	resp := struct {
-		Message string `json:"message"`
-		Data data `json:"data"`
-		Errors map[string][]string
-	}{}
+// DeleteGroup makes the request to the budget-micro-service to delete the
+// group identified by the UUID.
+func (s *Service) DeleteGroup(UUID uuid.UUID) dutil.Error {
+	return s.DeleteGroupContext(context.Background(), UUID)
+}
+
+// DeleteGroupContext is the same as DeleteGroup but uses the context ctx for
+// the requests made to the budget-micro-service.
+func (s *Service) DeleteGroupContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	_, e := exchange[struct{}](ctx, s, call{
+		name:        "DeleteGroup",
+		method:      "DELETE",
+		path:        "/group/-",
+		query:       url.Values{"uuid": {UUID.String()}},
+		invalidates: []string{tag("group", UUID), tag("items", UUID)},
+	})
+	return e
+}
 
-	res, e := s.newRequest("GET", s.URL.String(), nil, nil)
-	if e != nil {
-		return nil, e
+// MoveGroup makes the request to the budget-micro-service to move the group
+// identified by the UUID, together with its sub-groups, to the budget
+// identified by budgetUUID. If parentUUID is uuid.Nil the group becomes a
+// top-level group of the budget, otherwise it becomes a sub-group of the
+// parent group.
+func (s *Service) MoveGroup(UUID uuid.UUID, budgetUUID uuid.UUID, parentUUID uuid.UUID) (Group, dutil.Error) {
+	return s.MoveGroupContext(context.Background(), UUID, budgetUUID, parentUUID)
+}
+
+// MoveGroupContext is the same as MoveGroup but uses the context ctx for the
+// requests made to the budget-micro-service.
+func (s *Service) MoveGroupContext(ctx context.Context, UUID uuid.UUID, budgetUUID uuid.UUID, parentUUID uuid.UUID) (Group, dutil.Error) {
+	p := struct {
+		BudgetUUID uuid.UUID  `json:"budget_uuid"`
+		ParentUUID *uuid.UUID `json:"parent_uuid"`
+	}{
+		BudgetUUID: budgetUUID,
 	}
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return nil, e
+	if parentUUID != uuid.Nil {
+		p.ParentUUID = &parentUUID
 	}
 
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return nil, e
+	type data struct {
+		Group Group `json:"group"`
 	}
-	return resp.Data.Groups, nil
+	d, e := exchange[data](ctx, s, call{
+		name:        "MoveGroup",
+		method:      "PUT",
+		path:        "/group/-/move",
+		query:       url.Values{"uuid": {UUID.String()}},
+		payload:     p,
+		invalidates: []string{tag("group", UUID), tag("groups", budgetUUID)},
+	})
+	return d.Group, e
 }
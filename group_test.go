@@ -5,6 +5,10 @@ import (
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
 	"github.com/johannesscr/micro/microtest"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 )
 
@@ -251,3 +255,463 @@ func TestService_GetGroups(t *testing.T) {
 		})
 	}
 }
+
+func TestService_CreateGroup(t *testing.T) {
+	type E struct {
+		group Group
+		e     dutil.Error
+	}
+	tt := []struct {
+		name       string
+		budgetUUID uuid.UUID
+		parentUUID uuid.UUID
+		group      Group
+		exchange   *microtest.Exchange
+		E          E
+	}{
+		{
+			name:       "403 Permission Required",
+			budgetUUID: uuid.MustParse("2520f807-915e-41f6-9557-84500e1aebcc"),
+			group: Group{
+				Name:   "income",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body: `{
+						"message":"Forbidden: unable to process request",
+						"data":{},
+						"errors":{
+							"permission":["Please ensure you have permission"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"permission": {"Please ensure you have permission"},
+					},
+				},
+			},
+		},
+		{
+			name:       "200 Successful Sub-Group",
+			budgetUUID: uuid.MustParse("2520f807-915e-41f6-9557-84500e1aebcc"),
+			parentUUID: uuid.MustParse("52f2c725-2cdc-401a-abdd-66db5fd06789"),
+			group: Group{
+				Name:   "base salary",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"group created successfully",
+						"data":{
+							"group":{
+								"uuid":"b8448a78-6417-4fe2-849c-024622bc6106",
+								"name":"base salary",
+								"active":true,
+								"sub_groups":[]
+							}
+						},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				group: Group{
+					UUID:      uuid.MustParse("b8448a78-6417-4fe2-849c-024622bc6106"),
+					Name:      "base salary",
+					Active:    true,
+					SubGroups: []Group{},
+				},
+				e: nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add the budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			g, e := s.CreateGroup(tc.budgetUUID, tc.parentUUID, tc.group)
+			// test the error response
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			// test the group returned
+			seg := fmt.Sprintf("%v", tc.E.group)
+			sg := fmt.Sprintf("%v", g)
+			if seg != sg {
+				t.Errorf("expected group '%v' got '%v'", seg, sg)
+			}
+			// test the exchange request
+			if tc.exchange.Request.Method != "POST" {
+				t.Errorf("expected method '%v' got '%v'", "POST", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != "/group" {
+				t.Errorf("expected uri '%v' got '%v'", "/group", tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
+
+func TestService_CreateGroup_payload(t *testing.T) {
+	tt := []struct {
+		name       string
+		parentUUID uuid.UUID
+		body       string
+	}{
+		{
+			name:       "top-level group",
+			parentUUID: uuid.Nil,
+			body:       `{"budget_uuid":"2520f807-915e-41f6-9557-84500e1aebcc","group":{"uuid":"00000000-0000-0000-0000-000000000000","name":"income","active":true,"sub_groups":null}}`,
+		},
+		{
+			name:       "sub-group",
+			parentUUID: uuid.MustParse("52f2c725-2cdc-401a-abdd-66db5fd06789"),
+			body:       `{"budget_uuid":"2520f807-915e-41f6-9557-84500e1aebcc","parent_uuid":"52f2c725-2cdc-401a-abdd-66db5fd06789","group":{"uuid":"00000000-0000-0000-0000-000000000000","name":"income","active":true,"sub_groups":null}}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var body string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				xb, _ := ioutil.ReadAll(r.Body)
+				body = string(xb)
+				_, _ = w.Write([]byte(`{"message":"group created successfully","data":{},"errors":{}}`))
+			}))
+			defer srv.Close()
+
+			s := NewService("")
+			u, _ := url.Parse(srv.URL)
+			s.SetURL(u.Scheme, u.Host)
+
+			_, e := s.CreateGroup(
+				uuid.MustParse("2520f807-915e-41f6-9557-84500e1aebcc"),
+				tc.parentUUID,
+				Group{Name: "income", Active: true},
+			)
+			if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			if body != tc.body {
+				t.Errorf("expected body '%v' got '%v'", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestService_UpdateGroup(t *testing.T) {
+	type E struct {
+		group Group
+		e     dutil.Error
+	}
+	tt := []struct {
+		name     string
+		group    Group
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "404 Not Found",
+			group: Group{
+				UUID:   uuid.MustParse("52f2c725-2cdc-401a-abdd-66db5fd06789"),
+				Name:   "salary",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body: `{
+						"message":"NotFound: unable to process request",
+						"data":{},
+						"errors":{
+							"group":["not found"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 404,
+					Errors: map[string][]string{
+						"group": {"not found"},
+					},
+				},
+			},
+		},
+		{
+			name: "200 Successful",
+			group: Group{
+				UUID:   uuid.MustParse("52f2c725-2cdc-401a-abdd-66db5fd06789"),
+				Name:   "salary",
+				Active: false,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"group updated successfully",
+						"data":{
+							"group":{
+								"uuid":"52f2c725-2cdc-401a-abdd-66db5fd06789",
+								"name":"salary",
+								"active":false
+							}
+						},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				group: Group{
+					UUID:   uuid.MustParse("52f2c725-2cdc-401a-abdd-66db5fd06789"),
+					Name:   "salary",
+					Active: false,
+				},
+				e: nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add the budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			g, e := s.UpdateGroup(tc.group)
+			// test the error response
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			// test the group returned
+			seg := fmt.Sprintf("%v", tc.E.group)
+			sg := fmt.Sprintf("%v", g)
+			if seg != sg {
+				t.Errorf("expected group '%v' got '%v'", seg, sg)
+			}
+			// test the exchange request
+			if tc.exchange.Request.Method != "PUT" {
+				t.Errorf("expected method '%v' got '%v'", "PUT", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != "/group/-" {
+				t.Errorf("expected uri '%v' got '%v'", "/group/-", tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
+
+func TestService_DeleteGroup(t *testing.T) {
+	type E struct {
+		e        dutil.Error
+		exReqURI string
+	}
+	tt := []struct {
+		name     string
+		UUID     uuid.UUID
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "403 Permission Required",
+			UUID: uuid.MustParse("7dee09f0-2ba2-4b10-9c88-0c973ad4ebd0"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body: `{
+						"message":"Forbidden: unable to process request",
+						"data":{},
+						"errors":{
+							"permission":["Please ensure you have permission"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/group/-?uuid=7dee09f0-2ba2-4b10-9c88-0c973ad4ebd0",
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"permission": {"Please ensure you have permission"},
+					},
+				},
+			},
+		},
+		{
+			name: "200 Success",
+			UUID: uuid.MustParse("2c7b7d76-c9e0-49f8-b585-166ac70dba6f"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"group deleted successfully",
+						"data":{},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/group/-?uuid=2c7b7d76-c9e0-49f8-b585-166ac70dba6f",
+				e:        nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add the budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			e := s.DeleteGroup(tc.UUID)
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			if tc.exchange.Request.Method != "DELETE" {
+				t.Errorf("expected method '%v' got '%v'", "DELETE", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != tc.E.exReqURI {
+				t.Errorf("expected uri '%v' got '%v'", tc.E.exReqURI, tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
+
+func TestService_MoveGroup(t *testing.T) {
+	type E struct {
+		group    Group
+		e        dutil.Error
+		exReqURI string
+	}
+	tt := []struct {
+		name       string
+		UUID       uuid.UUID
+		budgetUUID uuid.UUID
+		parentUUID uuid.UUID
+		exchange   *microtest.Exchange
+		E          E
+	}{
+		{
+			name:       "400 Bad Request",
+			UUID:       uuid.MustParse("b8448a78-6417-4fe2-849c-024622bc6106"),
+			budgetUUID: uuid.MustParse("2520f807-915e-41f6-9557-84500e1aebcc"),
+			parentUUID: uuid.MustParse("b8448a78-6417-4fe2-849c-024622bc6106"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 400,
+					Body: `{
+						"message":"BadRequest: unable to process request",
+						"data":{},
+						"errors":{
+							"parent_uuid":["a group cannot be its own parent"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/group/-/move?uuid=b8448a78-6417-4fe2-849c-024622bc6106",
+				e: &dutil.Err{
+					Status: 400,
+					Errors: map[string][]string{
+						"parent_uuid": {"a group cannot be its own parent"},
+					},
+				},
+			},
+		},
+		{
+			name:       "200 Successful",
+			UUID:       uuid.MustParse("b8448a78-6417-4fe2-849c-024622bc6106"),
+			budgetUUID: uuid.MustParse("7cb47f06-0d96-494b-a847-a472e2c04d9d"),
+			parentUUID: uuid.Nil,
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"group moved successfully",
+						"data":{
+							"group":{
+								"uuid":"b8448a78-6417-4fe2-849c-024622bc6106",
+								"name":"base salary",
+								"active":true
+							}
+						},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/group/-/move?uuid=b8448a78-6417-4fe2-849c-024622bc6106",
+				group: Group{
+					UUID:   uuid.MustParse("b8448a78-6417-4fe2-849c-024622bc6106"),
+					Name:   "base salary",
+					Active: true,
+				},
+				e: nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add the budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			g, e := s.MoveGroup(tc.UUID, tc.budgetUUID, tc.parentUUID)
+			// test the error response
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			// test the group returned
+			seg := fmt.Sprintf("%v", tc.E.group)
+			sg := fmt.Sprintf("%v", g)
+			if seg != sg {
+				t.Errorf("expected group '%v' got '%v'", seg, sg)
+			}
+			// test the exchange request
+			if tc.exchange.Request.Method != "PUT" {
+				t.Errorf("expected method '%v' got '%v'", "PUT", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != tc.E.exReqURI {
+				t.Errorf("expected uri '%v' got '%v'", tc.E.exReqURI, tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
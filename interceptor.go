@@ -0,0 +1,109 @@
+package budget
+
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Request is a request to the budget-micro-service as it is seen by an
+// Interceptor. An Interceptor may modify the request before it is passed to
+// the next Handler, for example to add headers.
+type Request struct {
+	// Endpoint is the name of the Service method which makes the request,
+	// for example "GetBudgets".
+	Endpoint string
+	Method   string
+	// Path is the path of the endpoint on the budget-micro-service.
+	Path  string
+	Query url.Values
+	// Header holds the additional headers of the request, which are set on
+	// top of the Service's headers.
+	Header http.Header
+	// Payload is marshalled as the JSON body of the request if it is not
+	// nil.
+	Payload interface{}
+	// RequestID identifies the request and is sent as the X-Request-ID.
+	RequestID string
+}
+
+// Result is the decoded result of a request to the budget-micro-service.
+type Result struct {
+	// Status is the status of the response, or the status of the error if
+	// no response was received.
+	Status int
+	// Data is the decoded data of a successful response, its type is the
+	// type of the data returned by the Service method which made the
+	// request.
+	Data interface{}
+	// Err is the error of a failed request.
+	Err dutil.Error
+}
+
+// Handler makes the request req and returns its result.
+type Handler func(ctx context.Context, req *Request) *Result
+
+// Interceptor intercepts every request made by a Service. The interceptor
+// calls next to continue with the request, or returns a result of its own
+// without calling next, for example to inject faults in tests.
+type Interceptor func(ctx context.Context, req *Request, next Handler) *Result
+
+// WithInterceptors adds the interceptors to the chain of interceptors of the
+// Service. The interceptors are called in order, so the first interceptor
+// sees the request first and the result last.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(s *Service) {
+		s.interceptors = append(s.interceptors[:len(s.interceptors):len(s.interceptors)], interceptors...)
+	}
+}
+
+// intercept passes the request req through the service's interceptors to
+// the handler h.
+func (s *Service) intercept(ctx context.Context, req *Request, h Handler) *Result {
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		next, interceptor := h, s.interceptors[i]
+		h = func(ctx context.Context, req *Request) *Result {
+			return interceptor(ctx, req, next)
+		}
+	}
+	return h(ctx, req)
+}
+
+// LoggingInterceptor logs every request with the logger l, or the standard
+// logger if l is nil, after its result is received.
+func LoggingInterceptor(l *log.Logger) Interceptor {
+	if l == nil {
+		l = log.Default()
+	}
+	return func(ctx context.Context, req *Request, next Handler) *Result {
+		start := time.Now()
+		res := next(ctx, req)
+		l.Printf("- budget-service -> [ %v  %v ] <- %d %v (%s)",
+			req.Method, req.Path, res.Status, time.Since(start), req.RequestID)
+		return res
+	}
+}
+
+// TimingInterceptor calls the function f with the duration of every
+// request, including the retries of the request.
+func TimingInterceptor(f func(req *Request, res *Result, d time.Duration)) Interceptor {
+	return func(ctx context.Context, req *Request, next Handler) *Result {
+		start := time.Now()
+		res := next(ctx, req)
+		f(req, res, time.Since(start))
+		return res
+	}
+}
+
+// HeaderInterceptor sets the headers h on every request.
+func HeaderInterceptor(h http.Header) Interceptor {
+	return func(ctx context.Context, req *Request, next Handler) *Result {
+		for key, values := range h {
+			req.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,161 @@
+package budget
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/dottics/dutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithInterceptors(t *testing.T) {
+	var calls []string
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, req *Request, next Handler) *Result {
+			calls = append(calls, name+" request")
+			res := next(ctx, req)
+			calls = append(calls, name+" result")
+			return res
+		}
+	}
+	s := NewService("", WithInterceptors(trace("a"), trace("b")), WithInterceptors(trace("c")))
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "round-trip")
+		_, _ = w.Write([]byte(`{"message":"","data":{"alive":true},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	_, e := s.GetHome()
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+	xc := "a request, b request, c request, round-trip, c result, b result, a result"
+	if strings.Join(calls, ", ") != xc {
+		t.Errorf("expected '%v' got '%v'", xc, strings.Join(calls, ", "))
+	}
+}
+
+func TestWithInterceptors_result(t *testing.T) {
+	var req *Request
+	var res *Result
+	s := NewService("", WithInterceptors(func(ctx context.Context, r *Request, next Handler) *Result {
+		req = r
+		res = next(ctx, r)
+		return res
+	}))
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"message":"","data":{"event":{"name":"rent"}},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	ctx := ContextWithRequestID(context.Background(), "request-id")
+	ctx = ContextWithIdempotencyKey(ctx, "idempotency-key")
+	_, e := s.CreateEventContext(ctx, [16]byte{}, Event{})
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+	if req.Method != "POST" || req.Path != "/event" {
+		t.Errorf("expected '%v' got '%v %v'", "POST /event", req.Method, req.Path)
+	}
+	if req.RequestID != "request-id" {
+		t.Errorf("expected request id '%v' got '%v'", "request-id", req.RequestID)
+	}
+	if req.Header.Get(HeaderIdempotencyKey) != "idempotency-key" {
+		t.Errorf("expected idempotency key '%v' got '%v'", "idempotency-key", req.Header.Get(HeaderIdempotencyKey))
+	}
+	if res.Status != 200 {
+		t.Errorf("expected status %d got %d", 200, res.Status)
+	}
+	if res.Data == nil {
+		t.Errorf("expected the decoded data got %v", res.Data)
+	}
+}
+
+func TestWithInterceptors_fault(t *testing.T) {
+	var n int32
+	s := NewService("", WithInterceptors(func(ctx context.Context, req *Request, next Handler) *Result {
+		e := newError(req.RequestID, ErrUnavailable, 503, "fault", []string{"injected"})
+		return &Result{Status: 503, Err: e}
+	}))
+	closeServer := statusServer(s, &n, nil, 200)
+	defer closeServer()
+
+	alive, e := s.GetHome()
+	if alive {
+		t.Errorf("expected alive %v got %v", false, alive)
+	}
+	if !errors.Is(e, ErrUnavailable) {
+		t.Errorf("expected error kind %v got %v", ErrUnavailable, e)
+	}
+	if dutil.Inst(e).Status != 503 {
+		t.Errorf("expected status %d got %d", 503, dutil.Inst(e).Status)
+	}
+	if n != 0 {
+		t.Errorf("expected no requests got %d", n)
+	}
+}
+
+func TestHeaderInterceptor(t *testing.T) {
+	h := make(http.Header)
+	h.Set("X-Gateway", "gateway")
+	s := NewService("", WithInterceptors(HeaderInterceptor(h)))
+	headers := make(chan http.Header, 1)
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+		_, _ = w.Write([]byte(`{"message":"","data":{"alive":true},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	_, e := s.GetHome()
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+	if v := (<-headers).Get("X-Gateway"); v != "gateway" {
+		t.Errorf("expected '%v' got '%v'", "gateway", v)
+	}
+}
+
+func TestTimingInterceptor(t *testing.T) {
+	var d time.Duration
+	var status int
+	s := NewService("", WithInterceptors(TimingInterceptor(func(req *Request, res *Result, rd time.Duration) {
+		d = rd
+		status = res.Status
+	})))
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(404)
+		_, _ = w.Write([]byte(`{"message":"","data":{},"errors":{"budget":["not found"]}}`))
+	})
+	defer srv.Close()
+
+	_, _ = s.GetHome()
+	if d < 10*time.Millisecond {
+		t.Errorf("expected a duration of at least %v got %v", 10*time.Millisecond, d)
+	}
+	if status != 404 {
+		t.Errorf("expected status %d got %d", 404, status)
+	}
+}
+
+func TestLoggingInterceptor(t *testing.T) {
+	var n int32
+	buf := &bytes.Buffer{}
+	s := NewService("", WithInterceptors(LoggingInterceptor(log.New(buf, "", 0))))
+	closeServer := statusServer(s, &n, nil, 200)
+	defer closeServer()
+
+	ctx := ContextWithRequestID(context.Background(), "request-id")
+	_, _ = s.GetHomeContext(ctx)
+	xl := "- budget-service -> [ GET  / ] <- 200 "
+	if !strings.HasPrefix(buf.String(), xl) {
+		t.Errorf("expected '%v' got '%v'", xl, buf.String())
+	}
+	if !strings.HasSuffix(buf.String(), "(request-id)\n") {
+		t.Errorf("expected the request id got '%v'", buf.String())
+	}
+}
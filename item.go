@@ -1,6 +1,7 @@
 package budget
 
 import (
+	"context"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
 	"net/url"
@@ -11,37 +12,124 @@ import (
 //
 // uuid (uuid.UUID) for the group
 func (s *Service) GetItems(UUID uuid.UUID) (Items, dutil.Error) {
-	s.URL.Path = "/budget/group/-/item"
-	q := url.Values{
-		"uuid": {UUID.String()},
-	}
-	s.URL.RawQuery = q.Encode()
+	return s.GetItemsContext(context.Background(), UUID)
+}
 
+// GetItemsContext is the same as GetItems but uses the context ctx for the
+// requests made to the budget-micro-service.
+func (s *Service) GetItemsContext(ctx context.Context, UUID uuid.UUID) (Items, dutil.Error) {
 	type data struct {
 		Items Items `json:"items"`
 	}
-	resp := struct{
-		Message string `json:"message"`
-		Data data `json:"data"`
-		Errors map[string][]string `json:"errors"`
-	}{}
-
-	res, e := s.newRequest("GET", s.URL.String(), nil, nil)
-	if e != nil {
-		return nil, e
+	d, e := exchange[data](ctx, s, call{
+		name:   "GetItems",
+		method: "GET",
+		path:   "/budget/group/-/item",
+		query:  url.Values{"uuid": {UUID.String()}},
+		tags: func(v interface{}) []string {
+			return append(itemTags(v.(data).Items), tag("items", UUID))
+		},
+	})
+	return d.Items, e
+}
+
+// CreateItem makes the request to the budget-micro-service to create a new
+// item and associate that item with the group identified by groupUUID.
+func (s *Service) CreateItem(groupUUID uuid.UUID, item Item) (Item, dutil.Error) {
+	return s.CreateItemContext(context.Background(), groupUUID, item)
+}
+
+// CreateItemContext is the same as CreateItem but uses the context ctx for
+// the requests made to the budget-micro-service.
+func (s *Service) CreateItemContext(ctx context.Context, groupUUID uuid.UUID, item Item) (Item, dutil.Error) {
+	p := struct {
+		GroupUUID uuid.UUID `json:"group_uuid"`
+		Item      Item      `json:"item"`
+	}{
+		GroupUUID: groupUUID,
+		Item:      item,
 	}
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return nil, e
+
+	type data struct {
+		Item Item `json:"item"`
 	}
+	d, e := exchange[data](ctx, s, call{
+		name:        "CreateItem",
+		method:      "POST",
+		path:        "/item",
+		payload:     p,
+		invalidates: []string{tag("items", groupUUID)},
+	})
+	return d.Item, e
+}
 
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return nil, e
+// UpdateItem makes the request to the budget-micro-service to update an
+// existing item, the item is identified by its UUID.
+func (s *Service) UpdateItem(item Item) (Item, dutil.Error) {
+	return s.UpdateItemContext(context.Background(), item)
+}
+
+// UpdateItemContext is the same as UpdateItem but uses the context ctx for
+// the requests made to the budget-micro-service.
+func (s *Service) UpdateItemContext(ctx context.Context, item Item) (Item, dutil.Error) {
+	type data struct {
+		Item Item `json:"item"`
 	}
+	d, e := exchange[data](ctx, s, call{
+		name:        "UpdateItem",
+		method:      "PUT",
+		path:        "/item/-",
+		payload:     item,
+		invalidates: []string{tag("item", item.UUID)},
+	})
+	return d.Item, e
+}
+
+// DeleteItem makes the request to the budget-micro-service to delete the
+// item identified by the UUID.
+func (s *Service) DeleteItem(UUID uuid.UUID) dutil.Error {
+	return s.DeleteItemContext(context.Background(), UUID)
+}
 
-	return resp.Data.Items, nil
+// DeleteItemContext is the same as DeleteItem but uses the context ctx for
+// the requests made to the budget-micro-service.
+func (s *Service) DeleteItemContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	_, e := exchange[struct{}](ctx, s, call{
+		name:        "DeleteItem",
+		method:      "DELETE",
+		path:        "/item/-",
+		query:       url.Values{"uuid": {UUID.String()}},
+		invalidates: []string{tag("item", UUID), tag("events", UUID)},
+	})
+	return e
+}
+
+// MoveItem makes the request to the budget-micro-service to move the item
+// identified by the UUID to the group identified by groupUUID. All the
+// events of the item are moved together with the item.
+func (s *Service) MoveItem(UUID uuid.UUID, groupUUID uuid.UUID) (Item, dutil.Error) {
+	return s.MoveItemContext(context.Background(), UUID, groupUUID)
+}
+
+// MoveItemContext is the same as MoveItem but uses the context ctx for the
+// requests made to the budget-micro-service.
+func (s *Service) MoveItemContext(ctx context.Context, UUID uuid.UUID, groupUUID uuid.UUID) (Item, dutil.Error) {
+	p := struct {
+		GroupUUID uuid.UUID `json:"group_uuid"`
+	}{
+		GroupUUID: groupUUID,
+	}
+
+	type data struct {
+		Item Item `json:"item"`
+	}
+	d, e := exchange[data](ctx, s, call{
+		name:        "MoveItem",
+		method:      "PUT",
+		path:        "/item/-/move",
+		query:       url.Values{"uuid": {UUID.String()}},
+		payload:     p,
+		invalidates: []string{tag("item", UUID), tag("items", groupUUID)},
+	})
+	return d.Item, e
 }
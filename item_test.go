@@ -187,4 +187,415 @@ func TestService_GetItems(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestService_CreateItem(t *testing.T) {
+	type E struct {
+		item Item
+		e    dutil.Error
+	}
+	tt := []struct {
+		name      string
+		groupUUID uuid.UUID
+		item      Item
+		exchange  *microtest.Exchange
+		E         E
+	}{
+		{
+			name:      "403 Permission Required",
+			groupUUID: uuid.MustParse("52f2c725-2cdc-401a-abdd-66db5fd06789"),
+			item: Item{
+				Name:   "groceries",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body: `{
+						"message":"Forbidden: unable to process request",
+						"data":{},
+						"errors":{
+							"permission":["Please ensure you have permission"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"permission": {"Please ensure you have permission"},
+					},
+				},
+			},
+		},
+		{
+			name:      "200 Successful",
+			groupUUID: uuid.MustParse("52f2c725-2cdc-401a-abdd-66db5fd06789"),
+			item: Item{
+				Name:   "groceries",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"item created successfully",
+						"data":{
+							"item":{
+								"uuid":"0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d",
+								"name":"groceries",
+								"active":true
+							}
+						},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				item: Item{
+					UUID:   uuid.MustParse("0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d"),
+					Name:   "groceries",
+					Active: true,
+				},
+				e: nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			item, e := s.CreateItem(tc.groupUUID, tc.item)
+			// test errors
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected err: %s", e.Error())
+			}
+			// test item
+			if item.UUID != tc.E.item.UUID {
+				t.Errorf("expected uuid '%v' got '%v'", tc.E.item.UUID, item.UUID)
+			}
+			if item.Name != tc.E.item.Name {
+				t.Errorf("expected name '%v' got '%v'", tc.E.item.Name, item.Name)
+			}
+			if item.Active != tc.E.item.Active {
+				t.Errorf("expected active '%v' got '%v'", tc.E.item.Active, item.Active)
+			}
+			// test exchange request
+			if tc.exchange.Request.Method != "POST" {
+				t.Errorf("expected method '%v' got '%v'", "POST", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != "/item" {
+				t.Errorf("expected URI '%v' got '%v'", "/item", tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
+
+func TestService_UpdateItem(t *testing.T) {
+	type E struct {
+		item Item
+		e    dutil.Error
+	}
+	tt := []struct {
+		name     string
+		item     Item
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "404 Not Found",
+			item: Item{
+				UUID:   uuid.MustParse("0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d"),
+				Name:   "food",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body: `{
+						"message":"NotFound: unable to process request",
+						"data":{},
+						"errors":{
+							"item":["not found"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 404,
+					Errors: map[string][]string{
+						"item": {"not found"},
+					},
+				},
+			},
+		},
+		{
+			name: "200 Successful",
+			item: Item{
+				UUID:   uuid.MustParse("0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d"),
+				Name:   "food",
+				Active: true,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"item updated successfully",
+						"data":{
+							"item":{
+								"uuid":"0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d",
+								"name":"food",
+								"active":true
+							}
+						},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				item: Item{
+					UUID:   uuid.MustParse("0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d"),
+					Name:   "food",
+					Active: true,
+				},
+				e: nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			item, e := s.UpdateItem(tc.item)
+			// test errors
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected err: %s", e.Error())
+			}
+			// test item
+			if item.UUID != tc.E.item.UUID {
+				t.Errorf("expected uuid '%v' got '%v'", tc.E.item.UUID, item.UUID)
+			}
+			if item.Name != tc.E.item.Name {
+				t.Errorf("expected name '%v' got '%v'", tc.E.item.Name, item.Name)
+			}
+			// test exchange request
+			if tc.exchange.Request.Method != "PUT" {
+				t.Errorf("expected method '%v' got '%v'", "PUT", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != "/item/-" {
+				t.Errorf("expected URI '%v' got '%v'", "/item/-", tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
+
+func TestService_DeleteItem(t *testing.T) {
+	type E struct {
+		e        dutil.Error
+		exReqURI string
+	}
+	tt := []struct {
+		name     string
+		UUID     uuid.UUID
+		exchange *microtest.Exchange
+		E        E
+	}{
+		{
+			name: "403 Permission Required",
+			UUID: uuid.MustParse("7dee09f0-2ba2-4b10-9c88-0c973ad4ebd0"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body: `{
+						"message":"Forbidden: unable to process request",
+						"data":{},
+						"errors":{
+							"permission":["Please ensure you have permission"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/item/-?uuid=7dee09f0-2ba2-4b10-9c88-0c973ad4ebd0",
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"permission": {"Please ensure you have permission"},
+					},
+				},
+			},
+		},
+		{
+			name: "200 Success",
+			UUID: uuid.MustParse("2c7b7d76-c9e0-49f8-b585-166ac70dba6f"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"item deleted successfully",
+						"data":{},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/item/-?uuid=2c7b7d76-c9e0-49f8-b585-166ac70dba6f",
+				e:        nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			e := s.DeleteItem(tc.UUID)
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected err: %s", e.Error())
+			}
+			if tc.exchange.Request.Method != "DELETE" {
+				t.Errorf("expected method '%v' got '%v'", "DELETE", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != tc.E.exReqURI {
+				t.Errorf("expected URI '%v' got '%v'", tc.E.exReqURI, tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
+
+func TestService_MoveItem(t *testing.T) {
+	type E struct {
+		item     Item
+		e        dutil.Error
+		exReqURI string
+	}
+	tt := []struct {
+		name      string
+		UUID      uuid.UUID
+		groupUUID uuid.UUID
+		exchange  *microtest.Exchange
+		E         E
+	}{
+		{
+			name:      "404 Not Found",
+			UUID:      uuid.MustParse("0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d"),
+			groupUUID: uuid.MustParse("eea51d45-c9bd-45e2-bc80-010ecbb7a0d3"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body: `{
+						"message":"NotFound: unable to process request",
+						"data":{},
+						"errors":{
+							"group":["not found"]
+						}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/item/-/move?uuid=0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d",
+				e: &dutil.Err{
+					Status: 404,
+					Errors: map[string][]string{
+						"group": {"not found"},
+					},
+				},
+			},
+		},
+		{
+			name:      "200 Successful",
+			UUID:      uuid.MustParse("0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d"),
+			groupUUID: uuid.MustParse("eea51d45-c9bd-45e2-bc80-010ecbb7a0d3"),
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{
+						"message":"item moved successfully",
+						"data":{
+							"item":{
+								"uuid":"0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d",
+								"name":"groceries",
+								"active":true
+							}
+						},
+						"errors":{}
+					}`,
+				},
+			},
+			E: E{
+				exReqURI: "/item/-/move?uuid=0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d",
+				item: Item{
+					UUID:   uuid.MustParse("0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d"),
+					Name:   "groceries",
+					Active: true,
+				},
+				e: nil,
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// add budget-micro-service exchange
+			ms.Append(tc.exchange)
+
+			item, e := s.MoveItem(tc.UUID, tc.groupUUID)
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected err: %s", e.Error())
+			}
+			// test item
+			if item.UUID != tc.E.item.UUID {
+				t.Errorf("expected uuid '%v' got '%v'", tc.E.item.UUID, item.UUID)
+			}
+			if item.Name != tc.E.item.Name {
+				t.Errorf("expected name '%v' got '%v'", tc.E.item.Name, item.Name)
+			}
+			// test exchange request
+			if tc.exchange.Request.Method != "PUT" {
+				t.Errorf("expected method '%v' got '%v'", "PUT", tc.exchange.Request.Method)
+			}
+			if tc.exchange.Request.RequestURI != tc.E.exReqURI {
+				t.Errorf("expected URI '%v' got '%v'", tc.E.exReqURI, tc.exchange.Request.RequestURI)
+			}
+		})
+	}
+}
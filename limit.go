@@ -0,0 +1,165 @@
+package budget
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limit limits the calls made by a Service to the budget-micro-service.
+// A call which exceeds the limit waits until it is within the limit, or
+// until its context is done.
+type Limit struct {
+	// Rate is the number of calls per second which may be started. A rate
+	// of zero does not limit the rate of the calls.
+	Rate float64
+	// Burst is the number of calls which may be started at once, above the
+	// Rate, after no calls were made for a while. A burst less than one is
+	// a burst of one.
+	Burst int
+	// MaxInFlight is the maximum number of calls in flight at once. Zero
+	// does not limit the number of calls in flight.
+	MaxInFlight int
+}
+
+// WithLimit limits all the calls made by the Service, and by its views, see
+// ForUser, together.
+func WithLimit(l Limit) Option {
+	return func(s *Service) {
+		s.limits = s.limits.with("", l)
+	}
+}
+
+// WithEndpointLimit limits the calls made by the Service to the endpoint,
+// which is the name of a Service method, for example "GetEvents". The calls
+// to the endpoint are also limited by the limit of all the calls.
+func WithEndpointLimit(endpoint string, l Limit) Option {
+	return func(s *Service) {
+		s.limits = s.limits.with(endpoint, l)
+	}
+}
+
+// limits are the limiters of a Service, the limiter of all the calls has
+// the endpoint "".
+type limits map[string]*limiter
+
+// with returns a copy of the limits with the limit l of the endpoint.
+func (ls limits) with(endpoint string, l Limit) limits {
+	c := make(limits, len(ls)+1)
+	for key, value := range ls {
+		c[key] = value
+	}
+	c[endpoint] = newLimiter(l)
+	return c
+}
+
+// wait waits until the call to the endpoint is within the limits, or
+// until the context ctx is done. The returned function must be called once
+// the call is done.
+func (ls limits) wait(ctx context.Context, endpoint string) (func(), error) {
+	var releases []func()
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	for _, l := range []*limiter{ls[""], ls[endpoint]} {
+		if l == nil {
+			continue
+		}
+		r, err := l.wait(ctx)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+	return release, nil
+}
+
+// limiter limits calls with a token bucket and a semaphore for the calls
+// in flight. A limiter is safe for concurrent use.
+type limiter struct {
+	rate  float64
+	burst float64
+	// slots holds a value for every call in flight, it is nil if the number
+	// of calls in flight is not limited.
+	slots chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter creates the limiter of the limit l.
+func newLimiter(l Limit) *limiter {
+	burst := float64(l.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	lim := &limiter{
+		rate:   l.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+	if l.MaxInFlight > 0 {
+		lim.slots = make(chan struct{}, l.MaxInFlight)
+	}
+	return lim
+}
+
+// wait waits for a token and for a slot for the call, or until the
+// context ctx is done. The returned function releases the slot.
+func (l *limiter) wait(ctx context.Context) (func(), error) {
+	if d := l.reserve(); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			l.cancel()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket and returns the time to wait until
+// the token is available.
+func (l *limiter) reserve() time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token which was not used to the bucket.
+func (l *limiter) cancel() {
+	if l.rate <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
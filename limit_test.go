@@ -0,0 +1,127 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingServer blocks the requests to the path until unblock is closed
+// and records the maximum number of requests in flight at once.
+func blockingServer(s *Service, path string, unblock chan struct{}, max *int32) func() {
+	var n int32
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == path {
+			c := atomic.AddInt32(&n, 1)
+			for m := atomic.LoadInt32(max); c > m && !atomic.CompareAndSwapInt32(max, m, c); m = atomic.LoadInt32(max) {
+			}
+			<-unblock
+			atomic.AddInt32(&n, -1)
+		}
+		_, _ = w.Write([]byte(`{"message":"","data":{},"errors":{}}`))
+	})
+	return srv.Close
+}
+
+func TestWithEndpointLimit_maxInFlight(t *testing.T) {
+	s := NewService("", WithEndpointLimit("GetEvents", Limit{MaxInFlight: 2}))
+	unblock := make(chan struct{})
+	var max int32
+	closeServer := blockingServer(s, "/budget/group/item/-/event", unblock, &max)
+	defer closeServer()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, e := s.GetEvents(uuid.New())
+			if e != nil {
+				t.Errorf("unexpected error: %v", e)
+			}
+		}()
+	}
+
+	// the other endpoints are not limited by the endpoint's limit
+	time.Sleep(20 * time.Millisecond)
+	_, e := s.GetBudgets()
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+
+	close(unblock)
+	wg.Wait()
+	if max != 2 {
+		t.Errorf("expected at most %d requests in flight got %d", 2, max)
+	}
+}
+
+func TestWithLimit_rate(t *testing.T) {
+	s := NewService("", WithLimit(Limit{Rate: 100, Burst: 2}))
+	var n int32
+	closeServer := statusServer(s, &n, nil, 200)
+	defer closeServer()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		_, e := s.GetHome()
+		if e != nil {
+			t.Errorf("unexpected error: %v", e)
+		}
+	}
+	// the burst of two calls is immediate, the next four calls wait 10ms
+	// each
+	if d := time.Since(start); d < 35*time.Millisecond {
+		t.Errorf("expected the calls to take at least %v got %v", 35*time.Millisecond, d)
+	}
+}
+
+func TestWithLimit_context(t *testing.T) {
+	s := NewService("", WithLimit(Limit{MaxInFlight: 1}))
+	unblock := make(chan struct{})
+	var max int32
+	closeServer := blockingServer(s, "/", unblock, &max)
+	defer closeServer()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = s.GetHome()
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, e := s.GetBudgetsContext(ctx)
+	if !errors.Is(e, ErrTimeout) {
+		t.Errorf("expected error kind %v got %v", ErrTimeout, e)
+	}
+
+	close(unblock)
+	<-done
+	if max != 1 {
+		t.Errorf("expected %d request in flight got %d", 1, max)
+	}
+}
+
+func TestLimiter_cancel(t *testing.T) {
+	l := newLimiter(Limit{Rate: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := l.wait(ctx); err != nil {
+		t.Fatalf("expected the burst to be available got %v", err)
+	}
+	if _, err := l.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+	// the token of the cancelled call is returned to the bucket
+	if l.tokens < -0.1 || l.tokens > 0.1 {
+		t.Errorf("expected no tokens got %v", l.tokens)
+	}
+}
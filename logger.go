@@ -0,0 +1,104 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Logger logs the requests made by a Service as structured records of
+// key-value pairs. A *slog.Logger is a Logger.
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+// defaultLogger logs to the default slog logger, which is looked up for
+// every record so that slog.SetDefault also applies to existing services.
+type defaultLogger struct{}
+
+// Log logs the record to slog.Default.
+func (defaultLogger) Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	slog.Default().Log(ctx, level, msg, args...)
+}
+
+// redacted replaces the values which may not be logged.
+const redacted = "REDACTED"
+
+// WithLogger sets the logger of the Service, which logs every request made
+// to the budget-micro-service. A nil logger disables the logging. By
+// default the requests are logged to slog.Default.
+func WithLogger(l Logger) Option {
+	return func(s *Service) {
+		s.logger = l
+	}
+}
+
+// WithSensitiveParams marks the query parameters with the names as
+// sensitive, so that their values are redacted when a request is logged.
+func WithSensitiveParams(names ...string) Option {
+	return func(s *Service) {
+		// the names of the service are shared with its views, so they are
+		// copied rather than modified
+		sensitive := make(map[string]bool, len(s.sensitive)+len(names))
+		for name := range s.sensitive {
+			sensitive[name] = true
+		}
+		for _, name := range names {
+			sensitive[name] = true
+		}
+		s.sensitive = sensitive
+	}
+}
+
+// logRequest logs a single attempt of the request req, which took the
+// duration d. A response with a server error is logged as a warning and a
+// request which failed without a response is logged as an error.
+//
+// The headers of the request are never logged, so the X-User-Token cannot
+// be logged, and the values of the sensitive query parameters are redacted.
+func (s *Service) logRequest(req *http.Request, res *http.Response, err error, d time.Duration) {
+	if s.logger == nil {
+		return
+	}
+	args := []any{
+		"method", req.Method,
+		"path", req.URL.Path,
+		"query", s.redactQuery(req.URL.Query()),
+		"duration", d,
+		"request_id", req.Header.Get(HeaderRequestID),
+	}
+	level := slog.LevelInfo
+	switch {
+	case err != nil:
+		level = slog.LevelError
+		// the error of the client includes the URL, with the values of the
+		// sensitive query parameters, so only the underlying error is logged
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		args = append(args, "error", err.Error())
+	case res.StatusCode >= 500:
+		level = slog.LevelWarn
+		args = append(args, "status", res.StatusCode)
+	default:
+		args = append(args, "status", res.StatusCode)
+	}
+	s.logger.Log(req.Context(), level, "budget-service request", args...)
+}
+
+// redactQuery returns the encoded query q with the values of the service's
+// sensitive query parameters redacted.
+func (s *Service) redactQuery(q url.Values) string {
+	for name, values := range q {
+		if s.sensitive[name] {
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	return q.Encode()
+}
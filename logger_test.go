@@ -0,0 +1,162 @@
+package budget
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// recordLogger records the levels of the records it logs.
+type recordLogger struct {
+	levels []slog.Level
+}
+
+func (l *recordLogger) Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l.levels = append(l.levels, level)
+}
+
+func TestWithLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+	s := NewService("secret-token", WithLogger(l), WithSensitiveParams("uuid"))
+	var n int32
+	closeServer := statusServer(s, &n, nil, 200)
+	defer closeServer()
+
+	ctx := ContextWithRequestID(context.Background(), "request-id")
+	_, _ = exchange[struct{}](ctx, s, call{
+		method: "GET",
+		path:   "/budget/-",
+		query:  url.Values{"uuid": {"budget-uuid"}, "page": {"2"}},
+	})
+
+	record := map[string]interface{}{}
+	err := json.Unmarshal(buf.Bytes(), &record)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	xr := map[string]interface{}{
+		"level":      "INFO",
+		"msg":        "budget-service request",
+		"method":     "GET",
+		"path":       "/budget/-",
+		"query":      "page=2&uuid=REDACTED",
+		"status":     float64(200),
+		"request_id": "request-id",
+	}
+	for key, value := range xr {
+		if record[key] != value {
+			t.Errorf("expected %v '%v' got '%v'", key, value, record[key])
+		}
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Errorf("expected the duration got '%v'", buf.String())
+	}
+	for _, secret := range []string{"secret-token", "budget-uuid"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("expected '%v' to be redacted got '%v'", secret, buf.String())
+		}
+	}
+}
+
+func TestWithLogger_error(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+	s := NewService("", WithLogger(l), WithSensitiveParams("uuid"))
+	var n int32
+	closeServer := statusServer(s, &n, nil, 200)
+	// the request fails without a response
+	closeServer()
+
+	_, _ = exchange[struct{}](context.Background(), s, call{
+		method: "GET",
+		path:   "/budget/-",
+		query:  url.Values{"uuid": {"budget-uuid"}},
+	})
+
+	record := map[string]interface{}{}
+	err := json.Unmarshal(buf.Bytes(), &record)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if record["level"] != "ERROR" || record["error"] == "" {
+		t.Errorf("expected an error record got '%v'", buf.String())
+	}
+	if strings.Contains(buf.String(), "budget-uuid") {
+		t.Errorf("expected '%v' to be redacted got '%v'", "budget-uuid", buf.String())
+	}
+}
+
+func TestWithLogger_levels(t *testing.T) {
+	tt := []struct {
+		name     string
+		statuses []int
+		refused  bool
+		E        []slog.Level
+	}{
+		{
+			name:     "success",
+			statuses: []int{200},
+			E:        []slog.Level{slog.LevelInfo},
+		},
+		{
+			name:     "client error",
+			statuses: []int{404},
+			E:        []slog.Level{slog.LevelInfo},
+		},
+		{
+			name:     "server error",
+			statuses: []int{503},
+			E:        []slog.Level{slog.LevelWarn},
+		},
+		{
+			name:    "connection refused",
+			refused: true,
+			E:       []slog.Level{slog.LevelError},
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &recordLogger{}
+			s := NewService("", WithLogger(l))
+			var n int32
+			closeServer := statusServer(s, &n, nil, append(tc.statuses, 200)...)
+			if tc.refused {
+				closeServer()
+			} else {
+				defer closeServer()
+			}
+
+			_, _ = s.GetHome()
+			if len(l.levels) != len(tc.E) {
+				t.Fatalf("test %v: expected %d records got %d", i, len(tc.E), len(l.levels))
+			}
+			for j, level := range tc.E {
+				if l.levels[j] != level {
+					t.Errorf("test %v: expected level %v got %v", i, level, l.levels[j])
+				}
+			}
+		})
+	}
+}
+
+func TestWithLogger_nil(t *testing.T) {
+	s := NewService("", WithLogger(nil))
+	var n int32
+	closeServer := statusServer(s, &n, nil, 200)
+	defer closeServer()
+
+	_, e := s.GetHome()
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+	if atomic.LoadInt32(&n) != 1 {
+		t.Errorf("expected 1 request got %d", n)
+	}
+}
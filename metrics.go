@@ -0,0 +1,192 @@
+package budget
+
+import (
+	"errors"
+	"expvar"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Observation is the result of a single call made by a Service, including
+// all the retries of the call.
+type Observation struct {
+	// Endpoint is the name of the Service method which made the call, for
+	// example "GetBudgets".
+	Endpoint string
+	// Status is the status of the response, or the status of the error if
+	// no response was received.
+	Status int
+	// Kind is the kind of error of a failed call, one of the Err
+	// variables, or nil.
+	Kind error
+	// Err reports whether the call failed.
+	Err bool
+	// Duration is the duration of the call.
+	Duration time.Duration
+}
+
+// Collector collects the observations of the calls made by a Service, for
+// example to bridge them to a metrics system. A Collector is used by many
+// calls at once, so it must be safe for concurrent use.
+type Collector interface {
+	Observe(o Observation)
+}
+
+// WithCollector sets the collector which observes every call made by the
+// Service.
+func WithCollector(c Collector) Option {
+	return func(s *Service) {
+		s.collector = c
+	}
+}
+
+// observe passes the result res of the request req, which took the
+// duration d, to the service's collector.
+func (s *Service) observe(req *Request, res *Result, d time.Duration) {
+	if s.collector == nil {
+		return
+	}
+	o := Observation{
+		Endpoint: req.Endpoint,
+		Status:   res.Status,
+		Err:      res.Err != nil,
+		Duration: d,
+	}
+	var e *Error
+	if errors.As(res.Err, &e) {
+		o.Kind = e.Kind
+	}
+	s.collector.Observe(o)
+}
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms of
+// an ExpvarCollector.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// ExpvarCollector is a Collector which publishes the metrics of every
+// endpoint with expvar. For each endpoint it publishes:
+//
+//	requests  the number of calls
+//	errors    the number of failed calls by kind of error
+//	status    the number of calls by class of status, such as "2xx"
+//	latency   the cumulative histogram of the durations of the calls, by
+//	          upper bound, and the sum of the durations in seconds
+type ExpvarCollector struct {
+	m       *expvar.Map
+	buckets []time.Duration
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointVars
+}
+
+// endpointVars are the published metrics of a single endpoint.
+type endpointVars struct {
+	requests   expvar.Int
+	errors     expvar.Map
+	status     expvar.Map
+	latency    expvar.Map
+	latencySum expvar.Float
+}
+
+// NewExpvarCollector creates an ExpvarCollector which publishes the metrics
+// with expvar under the name, with the DefaultLatencyBuckets. Like
+// expvar.Publish it panics if the name is already in use.
+func NewExpvarCollector(name string) *ExpvarCollector {
+	c := newExpvarCollector()
+	expvar.Publish(name, c.m)
+	return c
+}
+
+// newExpvarCollector creates an ExpvarCollector, with the
+// DefaultLatencyBuckets, which has not been published.
+func newExpvarCollector() *ExpvarCollector {
+	return &ExpvarCollector{
+		m:         new(expvar.Map).Init(),
+		buckets:   DefaultLatencyBuckets,
+		endpoints: make(map[string]*endpointVars),
+	}
+}
+
+// Observe records the observation o with the metrics of its endpoint.
+func (c *ExpvarCollector) Observe(o Observation) {
+	v := c.endpoint(o.Endpoint)
+	v.requests.Add(1)
+	if o.Err {
+		v.errors.Add(kindName(o.Kind), 1)
+	}
+	v.status.Add(statusClass(o.Status), 1)
+	for _, b := range c.buckets {
+		if o.Duration <= b {
+			v.latency.Add(b.String(), 1)
+		}
+	}
+	v.latency.Add("+Inf", 1)
+	v.latencySum.Add(o.Duration.Seconds())
+}
+
+// endpoint returns the metrics of the endpoint, which are published the
+// first time the endpoint is observed.
+func (c *ExpvarCollector) endpoint(name string) *endpointVars {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.endpoints[name]
+	if !ok {
+		v = &endpointVars{}
+		v.errors.Init()
+		v.status.Init()
+		v.latency.Init()
+		m := new(expvar.Map).Init()
+		m.Set("requests", &v.requests)
+		m.Set("errors", &v.errors)
+		m.Set("status", &v.status)
+		m.Set("latency", &v.latency)
+		m.Set("latency_seconds_sum", &v.latencySum)
+		c.m.Set(name, m)
+		c.endpoints[name] = v
+	}
+	return v
+}
+
+// kindNames are the names of the kinds of errors in the metrics.
+var kindNames = map[error]string{
+	ErrNotFound:     "not_found",
+	ErrUnauthorized: "unauthorized",
+	ErrForbidden:    "forbidden",
+	ErrValidation:   "validation",
+	ErrConflict:     "conflict",
+	ErrUnavailable:  "unavailable",
+	ErrDecode:       "decode",
+	ErrCanceled:     "canceled",
+	ErrTimeout:      "timeout",
+	ErrCircuitOpen:  "circuit_open",
+}
+
+// kindName returns the name of the kind of error, or "other" for an error
+// of no well-known kind.
+func kindName(kind error) string {
+	if name, ok := kindNames[kind]; ok {
+		return name
+	}
+	return "other"
+}
+
+// statusClass returns the class of the status, such as "2xx".
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "other"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
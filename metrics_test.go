@@ -0,0 +1,164 @@
+package budget
+
+import (
+	"encoding/json"
+	"expvar"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+)
+
+// observations is a Collector which records the observations.
+type observations struct {
+	mu sync.Mutex
+	xo []Observation
+}
+
+func (c *observations) Observe(o Observation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.xo = append(c.xo, o)
+}
+
+func TestWithCollector(t *testing.T) {
+	type E struct {
+		endpoint string
+		status   int
+		kind     error
+		err      bool
+	}
+	tt := []struct {
+		name   string
+		status int
+		call   func(s *Service)
+		E      E
+	}{
+		{
+			name:   "GetBudgets",
+			status: 200,
+			call: func(s *Service) {
+				_, _ = s.GetBudgets()
+			},
+			E: E{endpoint: "GetBudgets", status: 200},
+		},
+		{
+			name:   "GetEvents not found",
+			status: 404,
+			call: func(s *Service) {
+				_, _ = s.GetEvents(uuid.New())
+			},
+			E: E{endpoint: "GetEvents", status: 404, kind: ErrNotFound, err: true},
+		},
+		{
+			name:   "CreateEvent failed",
+			status: 500,
+			call: func(s *Service) {
+				_, _ = s.CreateEvent(uuid.New(), Event{})
+			},
+			E: E{endpoint: "CreateEvent", status: 500, err: true},
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &observations{}
+			s := NewService("", WithCollector(c))
+			var n int32
+			closeServer := statusServer(s, &n, nil, tc.status)
+			defer closeServer()
+
+			tc.call(s)
+			if len(c.xo) != 1 {
+				t.Fatalf("test %v: expected 1 observation got %d", i, len(c.xo))
+			}
+			o := c.xo[0]
+			if o.Endpoint != tc.E.endpoint {
+				t.Errorf("test %v: expected endpoint '%v' got '%v'", i, tc.E.endpoint, o.Endpoint)
+			}
+			if o.Status != tc.E.status {
+				t.Errorf("test %v: expected status %d got %d", i, tc.E.status, o.Status)
+			}
+			if o.Kind != tc.E.kind {
+				t.Errorf("test %v: expected kind %v got %v", i, tc.E.kind, o.Kind)
+			}
+			if o.Err != tc.E.err {
+				t.Errorf("test %v: expected err %v got %v", i, tc.E.err, o.Err)
+			}
+		})
+	}
+}
+
+func TestExpvarCollector(t *testing.T) {
+	// the collector is not published, so that the test can run more than
+	// once
+	c := newExpvarCollector()
+	c.Observe(Observation{Endpoint: "GetGroups", Status: 200, Duration: 7 * time.Millisecond})
+	c.Observe(Observation{Endpoint: "GetGroups", Status: 503, Kind: ErrUnavailable, Err: true, Duration: 2 * time.Second})
+	c.Observe(Observation{Endpoint: "GetGroups", Status: 500, Err: true, Duration: time.Millisecond})
+
+	type metrics struct {
+		Requests   int            `json:"requests"`
+		Errors     map[string]int `json:"errors"`
+		Status     map[string]int `json:"status"`
+		Latency    map[string]int `json:"latency"`
+		LatencySum float64        `json:"latency_seconds_sum"`
+	}
+	m := map[string]metrics{}
+	err := json.Unmarshal([]byte(c.m.String()), &m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := m["GetGroups"]
+	if g.Requests != 3 {
+		t.Errorf("expected %d requests got %d", 3, g.Requests)
+	}
+	xe := map[string]int{"unavailable": 1, "other": 1}
+	for key, n := range xe {
+		if g.Errors[key] != n {
+			t.Errorf("expected %d %v errors got %d", n, key, g.Errors[key])
+		}
+	}
+	xs := map[string]int{"2xx": 1, "5xx": 2}
+	for key, n := range xs {
+		if g.Status[key] != n {
+			t.Errorf("expected %d %v got %d", n, key, g.Status[key])
+		}
+	}
+	xl := map[string]int{"5ms": 1, "10ms": 2, "1s": 2, "2.5s": 3, "+Inf": 3}
+	for key, n := range xl {
+		if g.Latency[key] != n {
+			t.Errorf("expected latency %v %d got %d", key, n, g.Latency[key])
+		}
+	}
+	if g.LatencySum < 2.008 || g.LatencySum > 2.0081 {
+		t.Errorf("expected latency sum %v got %v", 2.008, g.LatencySum)
+	}
+}
+
+func TestNewExpvarCollector(t *testing.T) {
+	name := "budget-service-" + uuid.New().String()
+	c := NewExpvarCollector(name)
+	c.Observe(Observation{Endpoint: "GetHome", Status: 200})
+	if v := expvar.Get(name); v == nil || v.String() != c.m.String() {
+		t.Errorf("expected the metrics to be published as '%v' got '%v'", name, v)
+	}
+}
+
+func Test_statusClass(t *testing.T) {
+	tt := []struct {
+		status int
+		class  string
+	}{
+		{200, "2xx"},
+		{404, "4xx"},
+		{StatusCanceled, "4xx"},
+		{503, "5xx"},
+		{0, "other"},
+	}
+	for _, tc := range tt {
+		if class := statusClass(tc.status); class != tc.class {
+			t.Errorf("expected '%v' got '%v'", tc.class, class)
+		}
+	}
+}
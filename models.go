@@ -15,11 +15,19 @@ type Budget struct {
 
 type Budgets []Budget
 
+// BudgetTree is a budget together with all its groups, sub-groups, items
+// and events.
+type BudgetTree struct {
+	Budget Budget `json:"budget"`
+	Groups Groups `json:"groups"`
+}
+
 type Group struct {
 	UUID      uuid.UUID `json:"uuid"`
 	Name      string    `json:"name"`
 	Active    bool      `json:"active"`
 	SubGroups []Group   `json:"sub_groups"`
+	Items     Items     `json:"-"`
 }
 
 type Groups []Group
@@ -58,6 +66,57 @@ type Event struct {
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
 	Active    bool      `json:"active"`
+	// Recurrence is the rule by which the event occurs, if it is nil the
+	// event occurs once in every month between its StartDate and EndDate.
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// Exceptions skip, move or change the amount of single occurrences of
+	// the event.
+	Exceptions []Exception `json:"exceptions,omitempty"`
+	// AmountChanges change the Amount of the event from their effective
+	// dates onwards, so that the event remains a single series when its
+	// amount changes.
+	AmountChanges []AmountChange `json:"amount_changes,omitempty"`
+	// Escalation increases the amount of the event every year, if it is not
+	// nil.
+	Escalation *Escalation `json:"escalation,omitempty"`
+	// Proration scales the amount of the event in the months which the
+	// event covers partially, if it is not empty.
+	Proration Proration `json:"proration,omitempty"`
 }
 
+// AmountChange is the amount of an event from the EffectiveDate onwards,
+// until the effective date of the next change.
+type AmountChange struct {
+	EffectiveDate time.Time `json:"effective_date"`
+	Amount        float64   `json:"amount"`
+}
+
+// Escalation increases the amount of an event by the Rate every year,
+// compounded from the StartDate of the event, or from the effective date of
+// its amount change. For example a Rate of 0.06 increases the amount by 6%
+// a year.
+//
+// The amount escalates on the anniversary of the StartDate, or on the first
+// day of the Month if it is not zero.
+type Escalation struct {
+	Rate  float64    `json:"rate"`
+	Month time.Month `json:"month,omitempty"`
+}
+
+// Proration is the convention by which the amount of an event without a
+// Recurrence is scaled in the months which the event covers partially, such
+// as a salary which starts on the 25th. By default the full amount is
+// counted in every month which the event covers, even partially.
+type Proration string
+
+// The conventions of a Proration.
+const (
+	// ProrateCalendarDays scales the amount by the days covered of the
+	// days in the month.
+	ProrateCalendarDays Proration = "calendar"
+	// Prorate30360 scales the amount by the days covered of 30 days, where
+	// every month has 30 days and the last day of a month is its 30th day.
+	Prorate30360 Proration = "30/360"
+)
+
 type Events []Event
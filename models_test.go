@@ -108,3 +108,27 @@ func TestItem_MonthlyTotal(t *testing.T) {
 		})
 	}
 }
+
+func TestItem_MonthlyTotal_exceptions(t *testing.T) {
+	december := 250.0
+	movedTo := MustParse("2021-01-02")
+	item := Item{Events: Events{
+		Event{
+			StartDate:  MustParse("2020-01-31"),
+			EndDate:    MustParse("2021-12-31"),
+			Debit:      true,
+			Amount:     100,
+			Recurrence: &Recurrence{Frequency: Monthly, ByMonthDay: []int{-1}},
+			Exceptions: []Exception{
+				{Date: MustParse("2020-12-31"), MovedTo: &movedTo},
+				{Date: MustParse("2021-06-30"), Skip: true},
+				{Date: MustParse("2021-12-31"), Amount: &december},
+			},
+		},
+	}}
+
+	total := [12]float64{200, 100, 100, 100, 100, 0, 100, 100, 100, 100, 100, 250}
+	if x := item.MonthlyTotal(2021); x != total {
+		t.Errorf("expected '%v' got '%v'", total, x)
+	}
+}
@@ -0,0 +1,56 @@
+package budget
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout is the time limit of a request made by a Service, which is
+// created by NewService, when no other timeout is configured.
+const DefaultTimeout = 30 * time.Second
+
+// defaultClient is used by a Service which was not created by NewService.
+var defaultClient = &http.Client{
+	Timeout: DefaultTimeout,
+}
+
+// Option configures a Service when it is created by NewService.
+type Option func(s *Service)
+
+// WithHTTPClient sets the client the Service uses to make the requests to
+// the budget-micro-service. The client is copied, so that the other options
+// do not modify the client c.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *Service) {
+		if c == nil {
+			return
+		}
+		client := *c
+		s.client = &client
+	}
+}
+
+// WithTransport sets the transport of the Service's client. The transport
+// controls the connection pool and the dial, TLS handshake and response
+// header timeouts.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(s *Service) {
+		s.client.Transport = rt
+	}
+}
+
+// WithTimeout sets the time limit of each request made by the Service,
+// including reading the response body. A timeout of zero means no timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.client.Timeout = d
+	}
+}
+
+// httpClient returns the client the Service uses to make requests.
+func (s *Service) httpClient() *http.Client {
+	if s.client == nil {
+		return defaultClient
+	}
+	return s.client
+}
@@ -0,0 +1,140 @@
+package budget
+
+import (
+	"github.com/dottics/dutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc makes a function a http.RoundTripper.
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// mockServer creates a test server from the handler and points the service
+// to the server.
+func mockServer(s *Service, h http.HandlerFunc) *httptest.Server {
+	srv := httptest.NewServer(h)
+	u, _ := url.Parse(srv.URL)
+	s.SetURL(u.Scheme, u.Host)
+	return srv
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	var n int32
+	c := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&n, 1)
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+	s := NewService("", WithHTTPClient(c), WithTimeout(5*time.Second))
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"message":"","data":{"alive":true},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	alive, e := s.GetHome()
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+	if !alive {
+		t.Errorf("expected '%v' got '%v'", true, alive)
+	}
+	if n != 1 {
+		t.Errorf("expected %d requests through the client got %d", 1, n)
+	}
+	// the options may not modify the client given
+	if c.Timeout != 0 {
+		t.Errorf("expected timeout '%v' got '%v'", time.Duration(0), c.Timeout)
+	}
+	if s.httpClient().Timeout != 5*time.Second {
+		t.Errorf("expected timeout '%v' got '%v'", 5*time.Second, s.httpClient().Timeout)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	s := NewService("", WithTimeout(20*time.Millisecond))
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	defer srv.Close()
+
+	_, e := s.GetHome()
+	if e == nil {
+		t.Fatalf("expected error got nil")
+	}
+	ie := dutil.Inst(e)
+	if ie.Status != 504 {
+		t.Errorf("expected status %d got %d", 504, ie.Status)
+	}
+	if len(ie.Errors["timeout"]) != 1 {
+		t.Errorf("expected a timeout error got '%v'", ie.Errors)
+	}
+}
+
+func TestService_connectionRefused(t *testing.T) {
+	// find an address on which nothing is listening
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	host := l.Addr().String()
+	_ = l.Close()
+
+	s := NewService("")
+	s.SetURL("http", host)
+
+	alive, e := s.GetHome()
+	if alive {
+		t.Errorf("expected '%v' got '%v'", false, alive)
+	}
+	if e == nil {
+		t.Fatalf("expected error got nil")
+	}
+	ie := dutil.Inst(e)
+	if ie.Status != 503 {
+		t.Errorf("expected status %d got %d", 503, ie.Status)
+	}
+	if len(ie.Errors["request"]) != 1 {
+		t.Errorf("expected a request error got '%v'", ie.Errors)
+	}
+}
+
+func TestService_connectionReuse(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"message":"","data":{"alive":true},"errors":{}}`))
+	}))
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	s := NewService("", WithTransport(&http.Transport{}))
+	u, _ := url.Parse(srv.URL)
+	s.SetURL(u.Scheme, u.Host)
+
+	for i := 0; i < 3; i++ {
+		_, e := s.GetHome()
+		if e != nil {
+			t.Errorf("unexpected error: %v", e)
+		}
+	}
+	if conns != 1 {
+		t.Errorf("expected %d connection got %d", 1, conns)
+	}
+}
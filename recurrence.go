@@ -0,0 +1,253 @@
+package budget
+
+import (
+	"sort"
+	"time"
+)
+
+// Frequency is the period with which a recurring event occurs.
+type Frequency string
+
+// The frequencies of a Recurrence.
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
+)
+
+// Recurrence is the rule by which an event occurs on specific dates between
+// its StartDate and EndDate, for example:
+//
+//	weekly on a Friday         {Frequency: Weekly, ByDay: []time.Weekday{time.Friday}}
+//	fortnightly                {Frequency: Weekly, Interval: 2}
+//	quarterly on the 1st       {Frequency: Monthly, Interval: 3, ByMonthDay: []int{1}}
+//	annually                   {Frequency: Yearly}
+//	last Friday of every month {Frequency: Monthly, ByDay: []time.Weekday{time.Friday}, Nth: -1}
+//
+// An event without a Recurrence occurs once in every month which overlaps
+// its StartDate and EndDate.
+type Recurrence struct {
+	Frequency Frequency `json:"frequency"`
+	// Interval is the number of periods between the periods in which the
+	// event occurs, counted from the period of the StartDate. An interval
+	// less than one is an interval of one.
+	Interval int `json:"interval,omitempty"`
+	// ByDay are the weekdays on which the event occurs. A weekly event
+	// without weekdays occurs on the weekday of its StartDate.
+	ByDay []time.Weekday `json:"by_day,omitempty"`
+	// ByMonthDay are the days of the month on which a monthly or yearly
+	// event occurs, a negative day counts from the end of the month so -1
+	// is the last day of the month. A day after the end of a month is the
+	// last day of that month. An event without days, weekdays or Nth
+	// occurs on the day of the month of its StartDate.
+	ByMonthDay []int `json:"by_month_day,omitempty"`
+	// Nth limits the ByDay weekdays of a monthly or yearly event to the nth
+	// weekday of the month, a negative Nth counts from the end of the month
+	// so -1 is the last weekday of the month.
+	Nth int `json:"nth,omitempty"`
+}
+
+// Occurrence is a single occurrence of an event.
+type Occurrence struct {
+	Date   time.Time `json:"date"`
+	Amount float64   `json:"amount"`
+}
+
+// Exception changes a single occurrence of an event, the occurrence on the
+// Date. An exception either skips the occurrence, or moves the occurrence
+// to another date and/or overrides its amount.
+type Exception struct {
+	// Date is the date of the occurrence which is changed.
+	Date time.Time `json:"date"`
+	// Skip excludes the occurrence, the event does not occur on the Date.
+	Skip bool `json:"skip,omitempty"`
+	// MovedTo is the date to which the occurrence is moved, if it is not
+	// nil.
+	MovedTo *time.Time `json:"moved_to,omitempty"`
+	// Amount is the amount of the occurrence, instead of the amount of the
+	// event, if it is not nil.
+	Amount *float64 `json:"amount,omitempty"`
+}
+
+// Occurrences returns the occurrences of the event e between the dates
+// from and to, inclusive, in order of their dates. The dates of the
+// occurrences are the dates, in UTC, without the time of the day.
+//
+// An event without a Recurrence occurs once in every month which overlaps
+// its StartDate and EndDate, on the first day of the month or on its
+// StartDate.
+//
+// The exceptions of the event are applied to its occurrences, so an
+// occurrence which is moved from outside the dates to a date between the
+// dates is returned, while an occurrence which is moved away or skipped is
+// not.
+func (e Event) Occurrences(from time.Time, to time.Time) []Occurrence {
+	from, to = date(from), date(to)
+	start, end := date(e.StartDate), date(e.EndDate)
+	if from.After(start) {
+		start = from
+	}
+	if to.Before(end) {
+		end = to
+	}
+
+	var xo []Occurrence
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if !e.occursOn(d) {
+			continue
+		}
+		x, ok := e.exception(d)
+		if ok && (x.Skip || x.MovedTo != nil) {
+			continue
+		}
+		xo = append(xo, Occurrence{Date: d, Amount: e.amount(d, x)})
+	}
+
+	// the occurrences moved to a date between the dates
+	moved := false
+	for _, x := range e.Exceptions {
+		if x.Skip || x.MovedTo == nil {
+			continue
+		}
+		d, m := date(x.Date), date(*x.MovedTo)
+		if m.Before(from) || m.After(to) || !e.occurs(d) {
+			continue
+		}
+		xo = append(xo, Occurrence{Date: m, Amount: e.amount(d, &x)})
+		moved = true
+	}
+	if moved {
+		sort.SliceStable(xo, func(i, j int) bool {
+			return xo[i].Date.Before(xo[j].Date)
+		})
+	}
+	return xo
+}
+
+// occurs reports whether the event e occurs on the date d, before its
+// exceptions are applied.
+func (e Event) occurs(d time.Time) bool {
+	return !d.Before(date(e.StartDate)) && !d.After(date(e.EndDate)) && e.occursOn(d)
+}
+
+// exception returns the exception of the occurrence of the event e on the
+// date d, if any.
+func (e Event) exception(d time.Time) (*Exception, bool) {
+	for i := range e.Exceptions {
+		if date(e.Exceptions[i].Date).Equal(d) {
+			return &e.Exceptions[i], true
+		}
+	}
+	return nil, false
+}
+
+// amount returns the amount of the occurrence of the event e on the date d
+// with the exception x, which may be nil.
+func (e Event) amount(d time.Time, x *Exception) float64 {
+	if x != nil && x.Amount != nil {
+		return *x.Amount
+	}
+	return e.amountOn(d) * e.coverage(d)
+}
+
+// occursOn reports whether the event e occurs on the date d, which is
+// between its StartDate and EndDate.
+func (e Event) occursOn(d time.Time) bool {
+	start := date(e.StartDate)
+	r := e.Recurrence
+	if r == nil {
+		return d.Equal(start) || d.Day() == 1
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch r.Frequency {
+	case Daily:
+		return days(start, d)%interval == 0 && (len(r.ByDay) == 0 || r.onWeekday(d))
+	case Weekly:
+		if days(monday(start), monday(d))/7%interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return d.Weekday() == start.Weekday()
+		}
+		return r.onWeekday(d)
+	case Monthly:
+		months := (d.Year()-start.Year())*12 + int(d.Month()) - int(start.Month())
+		return months%interval == 0 && r.onDayOfMonth(d, start)
+	case Yearly:
+		return d.Month() == start.Month() && (d.Year()-start.Year())%interval == 0 && r.onDayOfMonth(d, start)
+	}
+	return false
+}
+
+// onWeekday reports whether the date d is one of the ByDay weekdays.
+func (r *Recurrence) onWeekday(d time.Time) bool {
+	for _, wd := range r.ByDay {
+		if d.Weekday() == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// onDayOfMonth reports whether the date d is one of the days of the month
+// on which a monthly or yearly event, which started on the date start,
+// occurs.
+func (r *Recurrence) onDayOfMonth(d time.Time, start time.Time) bool {
+	n := daysIn(d)
+	if len(r.ByDay) > 0 {
+		if !r.onWeekday(d) {
+			return false
+		}
+		// the position of the weekday in the month, from the start and
+		// from the end of the month
+		return r.Nth == 0 || r.Nth == (d.Day()-1)/7+1 || r.Nth == -((n-d.Day())/7+1)
+	}
+	if len(r.ByMonthDay) == 0 {
+		return d.Day() == monthDay(start.Day(), n)
+	}
+	for _, md := range r.ByMonthDay {
+		if d.Day() == monthDay(md, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDay returns the day of a month with n days of the day of the month
+// md, where a negative md counts from the end of the month and a day after
+// the end of the month is the last day of the month.
+func monthDay(md int, n int) int {
+	if md < 0 {
+		md = n + md + 1
+	}
+	if md > n {
+		return n
+	}
+	return md
+}
+
+// date returns the date of the time t, in UTC, without the time of the
+// day.
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// days returns the number of days from the date a to the date b.
+func days(a time.Time, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// monday returns the date of the Monday of the week of the date d.
+func monday(d time.Time) time.Time {
+	return d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+}
+
+// daysIn returns the number of days in the month of the date d.
+func daysIn(d time.Time) int {
+	return time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,326 @@
+package budget
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_yearArray_recurrence(t *testing.T) {
+	tt := []struct {
+		name  string
+		year  int
+		event Event
+		z     [12]float64
+	}{
+		{
+			name: "weekly",
+			year: 2021,
+			event: Event{
+				Amount:     10,
+				StartDate:  MustParse("2021-01-01"),
+				EndDate:    MustParse("2021-12-31"),
+				Recurrence: &Recurrence{Frequency: Weekly},
+			},
+			z: [12]float64{50, 40, 40, 50, 40, 40, 50, 40, 40, 50, 40, 50},
+		},
+		{
+			name: "weekly by day",
+			year: 2021,
+			event: Event{
+				Amount:     1,
+				StartDate:  MustParse("2021-03-01"),
+				EndDate:    MustParse("2021-03-31"),
+				Recurrence: &Recurrence{Frequency: Weekly, ByDay: []time.Weekday{time.Saturday, time.Sunday}},
+			},
+			z: [12]float64{0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "fortnightly",
+			year: 2021,
+			event: Event{
+				Amount:     100,
+				StartDate:  MustParse("2021-01-08"),
+				EndDate:    MustParse("2022-01-08"),
+				Recurrence: &Recurrence{Frequency: Weekly, Interval: 2},
+			},
+			z: [12]float64{200, 200, 200, 300, 200, 200, 200, 200, 200, 300, 200, 200},
+		},
+		{
+			name: "quarterly",
+			year: 2021,
+			event: Event{
+				Amount:     30,
+				StartDate:  MustParse("2020-11-15"),
+				EndDate:    MustParse("2021-12-31"),
+				Recurrence: &Recurrence{Frequency: Monthly, Interval: 3},
+			},
+			z: [12]float64{0, 30, 0, 0, 30, 0, 0, 30, 0, 0, 30, 0},
+		},
+		{
+			name: "annual",
+			year: 2021,
+			event: Event{
+				Amount:     1200,
+				StartDate:  MustParse("2019-06-30"),
+				EndDate:    MustParse("2030-01-01"),
+				Recurrence: &Recurrence{Frequency: Yearly},
+			},
+			z: [12]float64{0, 0, 0, 0, 0, 1200, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "biennial",
+			year: 2021,
+			event: Event{
+				Amount:     1200,
+				StartDate:  MustParse("2020-06-30"),
+				EndDate:    MustParse("2030-01-01"),
+				Recurrence: &Recurrence{Frequency: Yearly, Interval: 2},
+			},
+			z: [12]float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "monthly on the 31st",
+			year: 2021,
+			event: Event{
+				Amount:     5,
+				StartDate:  MustParse("2021-01-31"),
+				EndDate:    MustParse("2021-04-30"),
+				Recurrence: &Recurrence{Frequency: Monthly},
+			},
+			z: [12]float64{5, 5, 5, 5, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "monthly by month day",
+			year: 2021,
+			event: Event{
+				Amount:     5,
+				StartDate:  MustParse("2021-01-01"),
+				EndDate:    MustParse("2021-02-28"),
+				Recurrence: &Recurrence{Frequency: Monthly, ByMonthDay: []int{1, 15, -1}},
+			},
+			z: [12]float64{15, 15, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "last Friday of the month",
+			year: 2021,
+			event: Event{
+				Amount:     2,
+				StartDate:  MustParse("2021-01-01"),
+				EndDate:    MustParse("2021-12-31"),
+				Recurrence: &Recurrence{Frequency: Monthly, ByDay: []time.Weekday{time.Friday}, Nth: -1},
+			},
+			z: [12]float64{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
+		},
+		{
+			name: "daily",
+			year: 2021,
+			event: Event{
+				Amount:     1,
+				StartDate:  MustParse("2021-02-01"),
+				EndDate:    MustParse("2021-03-31"),
+				Recurrence: &Recurrence{Frequency: Daily},
+			},
+			z: [12]float64{0, 28, 31, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "unknown frequency",
+			year: 2021,
+			event: Event{
+				Amount:     1,
+				StartDate:  MustParse("2021-01-01"),
+				EndDate:    MustParse("2021-12-31"),
+				Recurrence: &Recurrence{Frequency: "hourly"},
+			},
+			z: [12]float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			z := yearArray(tc.year, tc.event)
+			if z != tc.z {
+				t.Errorf("expected '%v' got '%v'", tc.z, z)
+			}
+		})
+	}
+}
+
+func TestEvent_Occurrences(t *testing.T) {
+	tt := []struct {
+		name  string
+		event Event
+		from  time.Time
+		to    time.Time
+		dates []string
+	}{
+		{
+			name: "second Tuesday",
+			event: Event{
+				StartDate:  MustParse("2021-01-01"),
+				EndDate:    MustParse("2021-12-31"),
+				Recurrence: &Recurrence{Frequency: Monthly, ByDay: []time.Weekday{time.Tuesday}, Nth: 2},
+			},
+			from:  MustParse("2021-01-01"),
+			to:    MustParse("2021-03-31"),
+			dates: []string{"2021-01-12", "2021-02-09", "2021-03-09"},
+		},
+		{
+			name: "annual on a leap day",
+			event: Event{
+				StartDate:  MustParse("2020-02-29"),
+				EndDate:    MustParse("2022-12-31"),
+				Recurrence: &Recurrence{Frequency: Yearly},
+			},
+			from:  MustParse("2020-01-01"),
+			to:    MustParse("2022-12-31"),
+			dates: []string{"2020-02-29", "2021-02-28", "2022-02-28"},
+		},
+		{
+			name: "weekly between from and to",
+			event: Event{
+				StartDate:  MustParse("2021-01-04"),
+				EndDate:    MustParse("2021-12-31"),
+				Recurrence: &Recurrence{Frequency: Weekly, Interval: 2, ByDay: []time.Weekday{time.Monday, time.Thursday}},
+			},
+			from:  MustParse("2021-01-05"),
+			to:    MustParse("2021-01-21"),
+			dates: []string{"2021-01-07", "2021-01-18", "2021-01-21"},
+		},
+		{
+			name: "no recurrence",
+			event: Event{
+				StartDate: MustParse("2021-01-20"),
+				EndDate:   MustParse("2021-03-10"),
+			},
+			from:  MustParse("2021-01-01"),
+			to:    MustParse("2021-12-31"),
+			dates: []string{"2021-01-20", "2021-02-01", "2021-03-01"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			xo := tc.event.Occurrences(tc.from, tc.to)
+			if len(xo) != len(tc.dates) {
+				t.Fatalf("expected %d occurrences got %v", len(tc.dates), xo)
+			}
+			for i, o := range xo {
+				if o.Date.Format("2006-01-02") != tc.dates[i] {
+					t.Errorf("expected '%v' got '%v'", tc.dates[i], o.Date.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
+
+func TestEvent_recurrenceJSON(t *testing.T) {
+	ev := Event{}
+	err := json.Unmarshal([]byte(`{"name":"wages","recurrence":{"frequency":"weekly","interval":2,"by_day":[5]}}`), &ev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := ev.Recurrence
+	if r == nil || r.Frequency != Weekly || r.Interval != 2 || len(r.ByDay) != 1 || r.ByDay[0] != time.Friday {
+		t.Errorf("expected a fortnightly recurrence on Friday got %+v", r)
+	}
+
+	xb, _ := json.Marshal(Event{})
+	if strings.Contains(string(xb), "recurrence") {
+		t.Errorf("expected no recurrence got %s", xb)
+	}
+}
+
+func TestEvent_Occurrences_exceptions(t *testing.T) {
+	date := func(s string) *time.Time {
+		d := MustParse(s)
+		return &d
+	}
+	amount := func(f float64) *float64 {
+		return &f
+	}
+	gym := Event{
+		Amount:     50,
+		StartDate:  MustParse("2021-01-05"),
+		EndDate:    MustParse("2021-12-31"),
+		Recurrence: &Recurrence{Frequency: Monthly},
+	}
+
+	type occurrence struct {
+		date   string
+		amount float64
+	}
+	tt := []struct {
+		name       string
+		exceptions []Exception
+		from       time.Time
+		to         time.Time
+		E          []occurrence
+	}{
+		{
+			name: "no exceptions",
+			from: MustParse("2021-01-01"),
+			to:   MustParse("2021-03-31"),
+			E:    []occurrence{{"2021-01-05", 50}, {"2021-02-05", 50}, {"2021-03-05", 50}},
+		},
+		{
+			name:       "skipped",
+			exceptions: []Exception{{Date: MustParse("2021-02-05"), Skip: true}},
+			from:       MustParse("2021-01-01"),
+			to:         MustParse("2021-03-31"),
+			E:          []occurrence{{"2021-01-05", 50}, {"2021-03-05", 50}},
+		},
+		{
+			name:       "amount override",
+			exceptions: []Exception{{Date: MustParse("2021-03-05"), Amount: amount(75)}},
+			from:       MustParse("2021-01-01"),
+			to:         MustParse("2021-03-31"),
+			E:          []occurrence{{"2021-01-05", 50}, {"2021-02-05", 50}, {"2021-03-05", 75}},
+		},
+		{
+			name:       "moved within the dates",
+			exceptions: []Exception{{Date: MustParse("2021-01-05"), MovedTo: date("2021-02-20"), Amount: amount(60)}},
+			from:       MustParse("2021-01-01"),
+			to:         MustParse("2021-03-31"),
+			E:          []occurrence{{"2021-02-05", 50}, {"2021-02-20", 60}, {"2021-03-05", 50}},
+		},
+		{
+			name:       "moved into the dates",
+			exceptions: []Exception{{Date: MustParse("2021-04-05"), MovedTo: date("2021-03-30")}},
+			from:       MustParse("2021-01-01"),
+			to:         MustParse("2021-03-31"),
+			E:          []occurrence{{"2021-01-05", 50}, {"2021-02-05", 50}, {"2021-03-05", 50}, {"2021-03-30", 50}},
+		},
+		{
+			name:       "moved out of the dates",
+			exceptions: []Exception{{Date: MustParse("2021-03-05"), MovedTo: date("2021-04-01")}},
+			from:       MustParse("2021-01-01"),
+			to:         MustParse("2021-03-31"),
+			E:          []occurrence{{"2021-01-05", 50}, {"2021-02-05", 50}},
+		},
+		{
+			name:       "not an occurrence",
+			exceptions: []Exception{{Date: MustParse("2021-02-06"), MovedTo: date("2021-02-07"), Amount: amount(1)}},
+			from:       MustParse("2021-02-01"),
+			to:         MustParse("2021-02-28"),
+			E:          []occurrence{{"2021-02-05", 50}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ev := gym
+			ev.Exceptions = tc.exceptions
+			xo := ev.Occurrences(tc.from, tc.to)
+			if len(xo) != len(tc.E) {
+				t.Fatalf("expected %d occurrences got %v", len(tc.E), xo)
+			}
+			for i, o := range xo {
+				if o.Date.Format("2006-01-02") != tc.E[i].date || o.Amount != tc.E[i].amount {
+					t.Errorf("expected %v got %v %v", tc.E[i], o.Date.Format("2006-01-02"), o.Amount)
+				}
+			}
+		})
+	}
+}
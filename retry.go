@@ -0,0 +1,156 @@
+package budget
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HeaderIdempotencyKey is the header which identifies a request as a single
+// operation, so that the budget-micro-service performs the operation only
+// once, even if the request is received more than once.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// RetryPolicy configures how a Service retries requests which failed,
+// either before a response was received or with one of the
+// RetryableStatus responses.
+//
+// Only idempotent requests are retried: requests with one of the Methods,
+// and POST requests which have an idempotency key.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// including the first attempt. Less than two attempts disables retries.
+	MaxAttempts int
+	// MinBackoff is the time waited before the first retry, the time waited
+	// doubles for every next retry up to MaxBackoff.
+	MinBackoff time.Duration
+	// MaxBackoff is the longest time waited before a retry, including the
+	// time asked for by a Retry-After header. Zero does not limit the time
+	// waited.
+	MaxBackoff time.Duration
+	// Jitter is the fraction, between 0 and 1, of the backoff which is
+	// randomised so that retries from many clients are spread out.
+	Jitter float64
+	// RetryableStatus are the response statuses which are retried.
+	RetryableStatus []int
+	// Methods are the methods of the requests which may be retried. If no
+	// methods are given, GET, HEAD, OPTIONS, PUT and DELETE requests are
+	// retried.
+	Methods []string
+}
+
+// DefaultRetryPolicy retries a request up to two times when the
+// budget-micro-service is unavailable.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     3,
+	MinBackoff:      100 * time.Millisecond,
+	MaxBackoff:      2 * time.Second,
+	Jitter:          0.5,
+	RetryableStatus: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+}
+
+// idempotentMethods are the methods retried if a RetryPolicy has no methods.
+var idempotentMethods = []string{"GET", "HEAD", "OPTIONS", "PUT", "DELETE"}
+
+// WithRetryPolicy sets the policy the Service uses to retry failed
+// requests. By default a Service does not retry requests.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(s *Service) {
+		s.retry = p
+	}
+}
+
+// retryable reports whether the request, which is the attempt-th attempt,
+// should be retried given its response res or error err. A request which
+// was aborted by its context is never retried.
+func (p RetryPolicy) retryable(req *http.Request, res *http.Response, err error, attempt int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	if !p.idempotent(req) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	for _, status := range p.RetryableStatus {
+		if res.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotent reports whether the request may be sent more than once.
+func (p RetryPolicy) idempotent(req *http.Request) bool {
+	if req.Method == "POST" && req.Header.Get(HeaderIdempotencyKey) != "" {
+		return true
+	}
+	methods := p.Methods
+	if len(methods) == 0 {
+		methods = idempotentMethods
+	}
+	for _, m := range methods {
+		if req.Method == m {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the time to wait before the retry following the
+// attempt-th attempt. If the response res has a Retry-After header then the
+// time the budget-micro-service asked for is used, up to the MaxBackoff.
+func (p RetryPolicy) backoff(attempt int, res *http.Response) time.Duration {
+	if d, ok := retryAfter(res); ok {
+		if p.MaxBackoff > 0 && d > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+		return d
+	}
+
+	d := p.MinBackoff
+	// stop doubling before the backoff overflows
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		j := time.Duration(p.Jitter * float64(d))
+		d = d - j + time.Duration(rand.Int63n(int64(j)+1))
+	}
+	return d
+}
+
+// retryAfter returns the time to wait as given by the Retry-After header of
+// the response, which is either a number of seconds or a HTTP date.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		if seconds > math.MaxInt64/int(time.Second) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,307 @@
+package budget
+
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusServer responds with the statuses in order, the last status is
+// repeated for all the remaining requests.
+func statusServer(s *Service, n *int32, header http.Header, statuses ...int) func() {
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(n, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(statuses[i])
+		_, _ = w.Write([]byte(`{"message":"","data":{"alive":true},"errors":{"status":["unavailable"]}}`))
+	})
+	return srv.Close
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts:     3,
+		MinBackoff:      time.Millisecond,
+		MaxBackoff:      5 * time.Millisecond,
+		RetryableStatus: []int{502, 503},
+	}
+	type E struct {
+		attempts int32
+		status   int
+	}
+	tt := []struct {
+		name     string
+		policy   RetryPolicy
+		method   string
+		key      string
+		statuses []int
+		E        E
+	}{
+		{
+			name:     "no retry policy",
+			policy:   RetryPolicy{},
+			method:   "GET",
+			statuses: []int{503, 200},
+			E:        E{attempts: 1, status: 503},
+		},
+		{
+			name:     "GET retried until successful",
+			policy:   p,
+			method:   "GET",
+			statuses: []int{503, 502, 200},
+			E:        E{attempts: 3, status: 200},
+		},
+		{
+			name:     "GET retried until max attempts",
+			policy:   p,
+			method:   "GET",
+			statuses: []int{503},
+			E:        E{attempts: 3, status: 503},
+		},
+		{
+			name:     "status not retryable",
+			policy:   p,
+			method:   "DELETE",
+			statuses: []int{500, 200},
+			E:        E{attempts: 1, status: 500},
+		},
+		{
+			name:     "POST without idempotency key",
+			policy:   p,
+			method:   "POST",
+			statuses: []int{503, 200},
+			E:        E{attempts: 1, status: 503},
+		},
+		{
+			name:     "POST with idempotency key",
+			policy:   p,
+			method:   "POST",
+			key:      "e4f4b7a0-7d1c-4b9e-9d3c-0c5d4f1e2a3b",
+			statuses: []int{503, 200},
+			E:        E{attempts: 2, status: 200},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var n int32
+			s := NewService("", WithRetryPolicy(tc.policy))
+			closeSrv := statusServer(s, &n, nil, tc.statuses...)
+			defer closeSrv()
+
+			h := map[string][]string{}
+			if tc.key != "" {
+				h[HeaderIdempotencyKey] = []string{tc.key}
+			}
+			res, e := s.newRequest(context.Background(), tc.method, s.endpoint("/", nil), h, strings.NewReader(`{"name":"james"}`))
+			if e != nil {
+				t.Fatalf("unexpected error: %v", e)
+			}
+			_, _ = s.decode(res, nil)
+			if res.StatusCode != tc.E.status {
+				t.Errorf("expected status %d got %d", tc.E.status, res.StatusCode)
+			}
+			if n != tc.E.attempts {
+				t.Errorf("expected %d attempts got %d", tc.E.attempts, n)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_body(t *testing.T) {
+	var bodies []string
+	s := NewService("", WithRetryPolicy(RetryPolicy{
+		MaxAttempts:     2,
+		RetryableStatus: []int{503},
+	}))
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		xb := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(xb)
+		bodies = append(bodies, string(xb))
+		if len(bodies) == 1 {
+			w.WriteHeader(503)
+		}
+		_, _ = w.Write([]byte(`{"message":"","data":{},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	_, e := s.UpdateBudget(Budget{Name: "retried"})
+	if e != nil {
+		t.Fatalf("unexpected error: %v", e)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("expected %d requests got %d", 2, len(bodies))
+	}
+	if bodies[0] != bodies[1] || !strings.Contains(bodies[1], `"name":"retried"`) {
+		t.Errorf("expected the same body to be retried got '%v'", bodies)
+	}
+}
+
+func TestRetryPolicy_retryAfter(t *testing.T) {
+	var n int32
+	s := NewService("", WithRetryPolicy(RetryPolicy{
+		MaxAttempts:     2,
+		RetryableStatus: []int{503},
+	}))
+	h := http.Header{"Retry-After": {"1"}}
+	closeSrv := statusServer(s, &n, h, 503, 503, 200)
+	defer closeSrv()
+
+	// the deadline is before the time the service asked to retry after, so
+	// the failed attempt is returned without waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, e := s.GetHomeContext(ctx)
+	if e == nil || dutil.Inst(e).Status != 503 {
+		t.Errorf("expected status %d got '%v'", 503, e)
+	}
+	if n != 1 {
+		t.Errorf("expected %d attempts got %d", 1, n)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("expected no wait got '%v'", time.Since(start))
+	}
+
+	// without a deadline the retry waits as long as the service asked
+	start = time.Now()
+	alive, e := s.GetHome()
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+	if !alive {
+		t.Errorf("expected '%v' got '%v'", true, alive)
+	}
+	if n != 3 {
+		t.Errorf("expected %d attempts got %d", 3, n)
+	}
+	if time.Since(start) < time.Second {
+		t.Errorf("expected to wait at least '%v' got '%v'", time.Second, time.Since(start))
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+	}
+	tt := []struct {
+		attempt int
+		E       time.Duration
+	}{
+		{attempt: 1, E: 100 * time.Millisecond},
+		{attempt: 2, E: 200 * time.Millisecond},
+		{attempt: 3, E: 400 * time.Millisecond},
+		{attempt: 4, E: 800 * time.Millisecond},
+		{attempt: 5, E: time.Second},
+		{attempt: 10, E: time.Second},
+	}
+	for _, tc := range tt {
+		d := p.backoff(tc.attempt, nil)
+		if d != tc.E {
+			t.Errorf("attempt %d: expected '%v' got '%v'", tc.attempt, tc.E, d)
+		}
+	}
+
+	// the jitter keeps the backoff within the fraction of the backoff
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2, nil)
+		if d < 100*time.Millisecond || d > 200*time.Millisecond {
+			t.Errorf("expected backoff between '%v' and '%v' got '%v'", 100*time.Millisecond, 200*time.Millisecond, d)
+		}
+	}
+}
+
+func TestRetryPolicy_backoff_uncapped(t *testing.T) {
+	p := RetryPolicy{
+		MinBackoff: 100 * time.Millisecond,
+	}
+	tt := []struct {
+		attempt int
+		E       time.Duration
+	}{
+		{attempt: 1, E: 100 * time.Millisecond},
+		{attempt: 2, E: 200 * time.Millisecond},
+		{attempt: 5, E: 1600 * time.Millisecond},
+	}
+	for _, tc := range tt {
+		d := p.backoff(tc.attempt, nil)
+		if d != tc.E {
+			t.Errorf("attempt %d: expected '%v' got '%v'", tc.attempt, tc.E, d)
+		}
+	}
+	// the backoff does not overflow
+	if d := p.backoff(100, nil); d <= 0 {
+		t.Errorf("expected a positive backoff got '%v'", d)
+	}
+}
+
+func TestRetryPolicy_backoff_retryAfter(t *testing.T) {
+	res := &http.Response{Header: http.Header{"Retry-After": {"3600"}}}
+	tt := []struct {
+		name       string
+		maxBackoff time.Duration
+		E          time.Duration
+	}{
+		{name: "capped", maxBackoff: time.Second, E: time.Second},
+		{name: "within the cap", maxBackoff: 2 * time.Hour, E: time.Hour},
+		{name: "uncapped", maxBackoff: 0, E: time.Hour},
+	}
+	for _, tc := range tt {
+		p := RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: tc.maxBackoff}
+		if d := p.backoff(1, res); d != tc.E {
+			t.Errorf("%v: expected '%v' got '%v'", tc.name, tc.E, d)
+		}
+	}
+}
+
+func Test_retryAfter(t *testing.T) {
+	tt := []struct {
+		name  string
+		value string
+		ok    bool
+		min   time.Duration
+		max   time.Duration
+	}{
+		{name: "no header", value: "", ok: false},
+		{name: "seconds", value: "3", ok: true, min: 3 * time.Second, max: 3 * time.Second},
+		{name: "invalid", value: "soon", ok: false},
+		{
+			name:  "http date",
+			value: time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat),
+			ok:    true,
+			min:   8 * time.Second,
+			max:   10 * time.Second,
+		},
+		{
+			name:  "http date in the past",
+			value: time.Now().Add(-10 * time.Second).UTC().Format(http.TimeFormat),
+			ok:    true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tc.value != "" {
+				res.Header.Set("Retry-After", tc.value)
+			}
+			d, ok := retryAfter(res)
+			if ok != tc.ok {
+				t.Errorf("expected '%v' got '%v'", tc.ok, ok)
+			}
+			if d < tc.min || d > tc.max {
+				t.Errorf("expected between '%v' and '%v' got '%v'", tc.min, tc.max, d)
+			}
+		})
+	}
+}
@@ -1,33 +1,83 @@
 package budget
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/dottics/dutil"
 	"io"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 )
 
+// Service is the Microservice Package's client for the Budget microservice.
+//
+// A Service is safe for concurrent use by multiple goroutines. Every request
+// builds its own URL from the service's URL and its own copy of the
+// service's Header, so requests never modify the service. The service's
+// URL and Header should therefore be configured, using NewService and
+// SetURL, before the service is used concurrently.
 type Service struct {
 	Header http.Header
 	URL    url.URL
+	// client is used to make all the requests, so that the connections to
+	// the budget-micro-service are reused between requests.
+	client *http.Client
+	// retry is the policy used to retry failed requests.
+	retry RetryPolicy
+	// tokens provides the X-User-Token of every request, if it is nil the
+	// X-User-Token of the Header is used.
+	tokens TokenSource
+	// interceptors intercept every request made by the service, in order.
+	interceptors []Interceptor
+	// logger logs every request made by the service, if it is not nil.
+	logger Logger
+	// sensitive are the names of the query parameters which are redacted
+	// when a request is logged.
+	sensitive map[string]bool
+	// collector observes the result of every call made by the service, if
+	// it is not nil.
+	collector Collector
+	// breaker fails the calls fast while the budget-micro-service is
+	// unavailable, if it is not nil.
+	breaker *breaker
+	// limits limit the calls made by the service.
+	limits limits
+	// cache caches the data of the GET calls, if it is not nil.
+	cache *cache
+	// flights coalesce the identical GET requests in flight, if it is not
+	// nil.
+	flights *flights
 }
 
-func NewService(token string) *Service {
+// NewService creates a Service for the user identified by the token. The
+// URL of the budget-micro-service is read from the BUDGET_SERVICE_SCHEME and
+// BUDGET_SERVICE_HOST environment variables. The options opts are applied
+// in order after the defaults are set.
+func NewService(token string, opts ...Option) *Service {
 	s := &Service{
 		URL: url.URL{
 			Scheme: os.Getenv("BUDGET_SERVICE_SCHEME"),
 			Host:   os.Getenv("BUDGET_SERVICE_HOST"),
 		},
 		Header: make(http.Header),
+		client: &http.Client{
+			Timeout: DefaultTimeout,
+		},
+		logger: defaultLogger{},
 	}
 	// default budget-micro-service headers
 	(*s).Header.Set("Content-Type", "application/json")
-	(*s).Header.Set("X-User-Token", token)
+	(*s).Header.Set(HeaderUserToken, token)
 
+	for _, opt := range opts {
+		opt(s)
+	}
 	return s
 }
 
@@ -55,25 +105,183 @@ func (s *Service) SetEnv() error {
 	return nil
 }
 
+// endpoint returns the URL, as a string, of the path and query on the
+// budget-micro-service. The service's URL is copied and not modified, so
+// that concurrent requests do not interfere with each other.
+func (s *Service) endpoint(path string, q url.Values) string {
+	u := s.URL
+	u.Path = path
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// StatusCanceled is the status of the error returned when a request to the
+// budget-micro-service is aborted because its context is cancelled. The
+// status is not sent by the budget-micro-service, it is the non-standard
+// "Client Closed Request" status.
+const StatusCanceled = 499
+
+// contextErr returns the error for the request identified by id which was
+// aborted because its context was cancelled or its deadline exceeded. A
+// cancelled request has the status StatusCanceled and the error key
+// "canceled", a request which exceeded its deadline has the status 504 and
+// the error key "timeout".
+func contextErr(id string, err error) dutil.Error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return newError(id, ErrTimeout, http.StatusGatewayTimeout, "timeout", []string{err.Error()})
+	}
+	return newError(id, ErrCanceled, StatusCanceled, "canceled", []string{err.Error()})
+}
+
+// transportErr returns the error for the request identified by id which
+// failed before a response was received from the budget-micro-service, for
+// example when the connection is refused. A request which timed out has the
+// status 504 and the error key "timeout", any other failure has the status
+// 503 and the error key "request".
+func transportErr(id string, err error) dutil.Error {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return newError(id, ErrTimeout, http.StatusGatewayTimeout, "timeout", []string{err.Error()})
+	}
+	return newError(id, ErrUnavailable, http.StatusServiceUnavailable, "request", []string{err.Error()})
+}
+
 // NewRequest consistently maps and executes requests to the requirements
-// for the service and returns the response.
-func (s *Service) newRequest(method string, url string, headers map[string][]string, payload io.Reader) (*http.Response, dutil.Error) {
-	client := http.Client{}
-	req, _ := http.NewRequest(method, url, payload)
-	// set the default headers from the service
-	req.Header = s.Header
-	// set/override additional header iff necessary
-	for key, values := range headers {
-		req.Header.Set(key, values[0])
+// for the service and returns the response. The request is aborted when
+// the context ctx is cancelled or its deadline is exceeded. Failed requests
+// are retried as configured by the service's RetryPolicy.
+//
+// Every request is identified by the request ID of the context ctx, or by a
+// new request ID, which is sent as the X-Request-ID header and is the
+// RequestID of the errors returned.
+//
+// If the context has a user's token, or the service has a TokenSource, the
+// X-User-Token of the request is obtained from the source. When the token is rejected as unauthorized the
+// token is refreshed and the request is sent once more with the new token.
+func (s *Service) newRequest(ctx context.Context, method string, url string, headers map[string][]string, payload io.Reader) (*http.Response, dutil.Error) {
+	id := requestID(ctx)
+	// read the payload once, so that it can be sent again when the request
+	// is retried
+	var body []byte
+	if payload != nil {
+		xb, err := ioutil.ReadAll(payload)
+		if err != nil {
+			e := newError(id, nil, 500, "request", []string{err.Error()})
+			return nil, e
+		}
+		body = xb
+	}
+
+	tokens := s.tokenSource(ctx)
+	var token string
+	if tokens != nil {
+		t, e := fetchToken(ctx, id, tokens, false)
+		if e != nil {
+			return nil, e
+		}
+		token = t
+	}
+	refreshed := false
+
+	for attempt := 1; ; attempt++ {
+		req, e := s.request(ctx, id, method, url, headers, body)
+		if e != nil {
+			return nil, e
+		}
+		if tokens != nil {
+			req.Header.Set(HeaderUserToken, token)
+		}
+		res, err := s.do(req)
+
+		if err == nil && res.StatusCode == http.StatusUnauthorized && tokens != nil && !refreshed {
+			refreshed = true
+			t, e := fetchToken(ctx, id, tokens, true)
+			if e != nil {
+				discard(res)
+				return nil, e
+			}
+			// only a new token can be accepted, the same token is rejected
+			// again
+			if t != token {
+				token = t
+				discard(res)
+				// sending the request with the new token is not a retry
+				attempt--
+				continue
+			}
+		}
+
+		if !s.retry.retryable(req, res, err, attempt) {
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, contextErr(id, ctx.Err())
+				}
+				return nil, transportErr(id, err)
+			}
+			return res, nil
+		}
+
+		wait := s.retry.backoff(attempt, res)
+		// do not wait for a retry which cannot complete before the deadline,
+		// rather return the failed attempt
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			if err != nil {
+				return nil, transportErr(id, err)
+			}
+			return res, nil
+		}
+		if res != nil {
+			discard(res)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, contextErr(id, ctx.Err())
+		case <-t.C:
+		}
 	}
-	res, err := client.Do(req)
-	log.Printf("- budget-service -> [ %v  %v ] <- %d",
-		req.Method, req.URL.String(), res.StatusCode)
+}
+
+// discard drains and closes the body of the response res, which is not
+// used, so that the connection can be reused.
+func discard(res *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	_ = res.Body.Close()
+}
+
+// request creates a single request, identified by id, with a copy of the
+// service's headers and the additional headers, to send the body to the url.
+func (s *Service) request(ctx context.Context, id string, method string, url string, headers map[string][]string, body []byte) (*http.Request, dutil.Error) {
+	var payload io.Reader
+	if body != nil {
+		payload = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, payload)
 	if err != nil {
-		e := dutil.NewErr(500, "request", []string{err.Error()})
+		e := newError(id, nil, 500, "request", []string{err.Error()})
 		return nil, e
 	}
-	return res, nil
+	// set a copy of the default headers from the service, the request may
+	// not modify the service's headers as they are shared between requests
+	for key, values := range s.Header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	req.Header.Set(HeaderRequestID, id)
+	// set/override additional header iff necessary
+	for key, values := range headers {
+		req.Header.Set(key, values[0])
+	}
+	return req, nil
+}
+
+// do sends a single request to the budget-micro-service and logs it.
+func (s *Service) do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := s.httpClient().Do(req)
+	s.logRequest(req, res, err, time.Since(start))
+	return res, err
 }
 
 // decode is a function that decodes a body into a slice of bytes and error of
@@ -82,20 +290,25 @@ func (s *Service) newRequest(method string, url string, headers map[string][]str
 func (s *Service) decode(res *http.Response, v interface{}) ([]byte, dutil.Error) {
 	xb, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		e := dutil.NewErr(500, "read", []string{err.Error()})
+		_ = res.Body.Close()
+		// the body is read after the response is received, so the request
+		// can still be aborted while the body is read
+		if res.Request != nil && res.Request.Context().Err() != nil {
+			return nil, contextErr(responseRequestID(res), res.Request.Context().Err())
+		}
+		e := newError(responseRequestID(res), ErrDecode, 500, "read", []string{err.Error()})
 		return nil, e
 	}
 	err = res.Body.Close()
 	if err != nil {
-		e := dutil.NewErr(500, "decode", []string{err.Error()})
+		e := newError(responseRequestID(res), ErrDecode, 500, "decode", []string{err.Error()})
 		return nil, e
 	}
-	//log.Printf("BUDGET SERVICE DECODE: %s", string(xb))
 
 	if v != nil {
 		err = json.Unmarshal(xb, v)
 		if err != nil {
-			e := dutil.NewErr(500, "unmarshal", []string{err.Error()})
+			e := newError(responseRequestID(res), ErrDecode, 500, "unmarshal", []string{err.Error()})
 			return nil, e
 		}
 	}
@@ -106,29 +319,19 @@ func (s *Service) decode(res *http.Response, v interface{}) ([]byte, dutil.Error
 // GetHome is the health-check function which makes a request to the
 // budget-service to check that the service is still up and running.
 func (s *Service) GetHome() (bool, dutil.Error) {
-	s.URL.Path = "/"
-
-	resp := struct {
-		Message string              `json:"message"`
-		Data    interface{}         `json:"data"`
-		Errors  map[string][]string `json:"errors"`
-	}{}
+	return s.GetHomeContext(context.Background())
+}
 
-	res, e := s.newRequest("GET", s.URL.String(), nil, nil)
+// GetHomeContext is the same as GetHome but uses the context ctx for the
+// requests made to the budget-micro-service.
+func (s *Service) GetHomeContext(ctx context.Context) (bool, dutil.Error) {
+	_, e := exchange[struct{}](ctx, s, call{
+		name:   "GetHome",
+		method: "GET",
+		path:   "/",
+	})
 	if e != nil {
 		return false, e
 	}
-	_, e = s.decode(res, &resp)
-	if e != nil {
-		return false, e
-	}
-
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return false, e
-	}
 	return true, nil
 }
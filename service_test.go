@@ -1,14 +1,20 @@
 package budget
 
 import (
+	"context"
+	"fmt"
 	"github.com/dottics/dutil"
+	"github.com/google/uuid"
 	"github.com/johannesscr/micro/microtest"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewService(t *testing.T) {
@@ -154,7 +160,7 @@ func TestService_NewRequest(t *testing.T) {
 	p := strings.NewReader(`{"name":"james"}`)
 
 	// now to make the request
-	_, e := s.newRequest("PUT", s.URL.String(), h, p)
+	_, e := s.newRequest(context.Background(), "PUT", s.URL.String(), h, p)
 
 	if e != nil {
 		t.Errorf("unexpected error: %v", e)
@@ -318,6 +324,19 @@ func TestGetHome(t *testing.T) {
 				e: dutil.Err{},
 			},
 		},
+		{
+			name: "200 server alive without object data",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body: `{"message":"Welcome to the budget micro-service","data":"running","errors":{}}`,
+				},
+			},
+			E: E{
+				alive: true,
+				e: dutil.Err{},
+			},
+		},
 	}
 
 	s := NewService("")
@@ -340,4 +359,163 @@ func TestGetHome(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestService_newRequest_headers(t *testing.T) {
+	s := NewService("test-fake-token")
+	ms := microtest.MockServer(s)
+	defer ms.Server.Close()
+
+	ex := &microtest.Exchange{
+		Response: microtest.Response{
+			Status: 200,
+			Body:   `{"message":"successful request"}`,
+		},
+	}
+	ms.Append(ex)
+
+	h := map[string][]string{
+		"X-Random":     {"my-random-header"},
+		"X-User-Token": {"other-token"},
+	}
+	_, e := s.newRequest(context.Background(), "GET", s.endpoint("/my/path", nil), h, nil)
+	if e != nil {
+		t.Errorf("unexpected error: %v", e)
+	}
+	// the request headers may not modify the service's headers
+	if x := s.Header.Get("X-Random"); x != "" {
+		t.Errorf("expected '%v' got '%v'", "", x)
+	}
+	if x := s.Header.Get("X-User-Token"); x != "test-fake-token" {
+		t.Errorf("expected '%v' got '%v'", "test-fake-token", x)
+	}
+	if x := ex.Request.Header.Get("X-User-Token"); x != "other-token" {
+		t.Errorf("expected '%v' got '%v'", "other-token", x)
+	}
+	// the service's URL is not modified by the request
+	if s.URL.Path != "" || s.URL.RawQuery != "" {
+		t.Errorf("expected empty path and query got '%v' '%v'", s.URL.Path, s.URL.RawQuery)
+	}
+}
+
+// TestService_concurrent makes requests from many goroutines using the same
+// service, it should be run with the race detector: go test -race
+func TestService_concurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// echo the uuid and path of the request back as the budget or group
+		q := r.URL.Query()
+		body := fmt.Sprintf(
+			`{"message":"","data":{"budget":{"uuid":"%[1]s","name":"%[2]s"},"groups":[{"uuid":"%[1]s","name":"%[2]s"}]},"errors":{}}`,
+			q.Get("uuid"), r.URL.Path,
+		)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	s := NewService("test-fake-token")
+	u, _ := url.Parse(srv.URL)
+	s.SetURL(u.Scheme, u.Host)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			UUID := uuid.New()
+			if i%2 == 0 {
+				b, e := s.GetBudget(UUID)
+				if e != nil {
+					t.Errorf("unexpected error: %v", e)
+					return
+				}
+				if b.UUID != UUID || b.Name != "/budget/-" {
+					t.Errorf("expected '%v %v' got '%v %v'", UUID, "/budget/-", b.UUID, b.Name)
+				}
+				return
+			}
+			xg, e := s.GetGroups(UUID)
+			if e != nil {
+				t.Errorf("unexpected error: %v", e)
+				return
+			}
+			if len(xg) != 1 || xg[0].UUID != UUID || xg[0].Name != "/budget/-/group" {
+				t.Errorf("expected '[%v %v]' got '%v'", UUID, "/budget/-/group", xg)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if x := s.Header.Get("X-User-Token"); x != "test-fake-token" {
+		t.Errorf("expected '%v' got '%v'", "test-fake-token", x)
+	}
+}
+
+func TestService_GetHomeContext(t *testing.T) {
+	tt := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		E    dutil.Err
+	}{
+		{
+			name: "cancelled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			E: dutil.Err{
+				Status: StatusCanceled,
+				Errors: map[string][]string{
+					"canceled": {"context canceled"},
+				},
+			},
+		},
+		{
+			name: "deadline exceeded",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			E: dutil.Err{
+				Status: 504,
+				Errors: map[string][]string{
+					"timeout": {"context deadline exceeded"},
+				},
+			},
+		},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// respond slower than the deadline
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		_, _ = w.Write([]byte(`{"message":"","data":{"alive":true},"errors":{}}`))
+	}))
+	defer srv.Close()
+
+	s := NewService("")
+	u, _ := url.Parse(srv.URL)
+	s.SetURL(u.Scheme, u.Host)
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := tc.ctx()
+			defer cancel()
+
+			alive, e := s.GetHomeContext(ctx)
+			if alive {
+				t.Errorf("expected '%v' got '%v'", false, alive)
+			}
+			if e == nil {
+				t.Fatalf("expected error got nil")
+			}
+			if e.Error() != tc.E.Error() {
+				t.Errorf("expected '%v' got '%v'", tc.E.Error(), e.Error())
+			}
+			if dutil.Inst(e).Status != tc.E.Status {
+				t.Errorf("expected status %d got %d", tc.E.Status, dutil.Inst(e).Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package budget
+
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"net/http"
+)
+
+// HeaderUserToken is the header which authenticates the user on whose
+// behalf a request is made.
+const HeaderUserToken = "X-User-Token"
+
+// TokenSource provides the X-User-Token of each request made by a Service.
+// A TokenSource is used by many requests at once, so it must be safe for
+// concurrent use.
+type TokenSource interface {
+	// Token returns the token for a request. If refresh is true the
+	// previous token was rejected as unauthorized by the
+	// budget-micro-service and a new token should be obtained.
+	Token(ctx context.Context, refresh bool) (string, error)
+}
+
+// StaticToken is a TokenSource which always provides the same token, it
+// cannot be refreshed.
+type StaticToken string
+
+// Token returns the static token.
+func (t StaticToken) Token(ctx context.Context, refresh bool) (string, error) {
+	return string(t), nil
+}
+
+// TokenFunc is a TokenSource which calls the function to obtain a token,
+// for example from a session store.
+type TokenFunc func(ctx context.Context, refresh bool) (string, error)
+
+// Token calls the function f.
+func (f TokenFunc) Token(ctx context.Context, refresh bool) (string, error) {
+	return f(ctx, refresh)
+}
+
+// WithTokenSource sets the source of the X-User-Token of every request made
+// by the Service, which replaces the token given to NewService.
+func WithTokenSource(ts TokenSource) Option {
+	return func(s *Service) {
+		s.tokens = ts
+	}
+}
+
+// resolvedToken is the TokenSource of a request whose token was already
+// obtained from the source, so that the request is made with the token it
+// was identified by. A refreshed token is obtained from the source.
+type resolvedToken struct {
+	token  string
+	source TokenSource
+}
+
+// Token returns the token obtained, or a new token from the source if
+// refresh is true.
+func (t resolvedToken) Token(ctx context.Context, refresh bool) (string, error) {
+	if refresh {
+		return t.source.Token(ctx, true)
+	}
+	return t.token, nil
+}
+
+// fetchToken obtains a token from the TokenSource ts for the request
+// identified by id. If no token can be obtained the request is
+// unauthorized.
+func fetchToken(ctx context.Context, id string, ts TokenSource, refresh bool) (string, dutil.Error) {
+	t, err := ts.Token(ctx, refresh)
+	if err != nil {
+		e := newError(id, ErrUnauthorized, http.StatusUnauthorized, "token", []string{err.Error()})
+		return "", e
+	}
+	return t, nil
+}
+
+// ForUser returns a view of the Service which makes its requests on behalf
+// of the user identified by the token. The view shares the HTTP client, and
+// therefore the connections, and the configuration of the Service, but has
+// its own copy of the Header, so the token is never sent with the requests
+// of the Service or of the other views. Creating a view is cheap, so a view
+// can be created for every request of an API gateway.
+func (s *Service) ForUser(token string) *Service {
+	v := *s
+	v.Header = s.Header.Clone()
+	if v.Header == nil {
+		v.Header = make(http.Header)
+	}
+	v.Header.Set(HeaderUserToken, token)
+	// the token replaces the token source of the service
+	v.tokens = nil
+	return &v
+}
+
+// tokenSource returns the source of the X-User-Token for the requests made
+// with the context ctx. The user's token of the context takes precedence
+// over the TokenSource of the service. If neither is set nil is returned
+// and the X-User-Token of the Header is used.
+func (s *Service) tokenSource(ctx context.Context) TokenSource {
+	if ts, ok := ctx.Value(tokenSourceKey).(TokenSource); ok {
+		return ts
+	}
+	if token, ok := UserTokenFromContext(ctx); ok {
+		return StaticToken(token)
+	}
+	return s.tokens
+}
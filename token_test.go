@@ -0,0 +1,212 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// tokenServer accepts only the requests with the X-User-Token valid and
+// records the number of requests received in n.
+func tokenServer(s *Service, n *int32, valid string) func() {
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(n, 1)
+		if r.Header.Get(HeaderUserToken) != valid {
+			w.WriteHeader(401)
+			_, _ = w.Write([]byte(`{"message":"","data":{},"errors":{"auth":["unauthorized"]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":"","data":{"alive":true},"errors":{}}`))
+	})
+	return srv.Close
+}
+
+func TestService_TokenSource(t *testing.T) {
+	type E struct {
+		requests  int32
+		refreshes int32
+		alive     bool
+		kind      error
+	}
+	tt := []struct {
+		name   string
+		tokens []string
+		err    error
+		E      E
+	}{
+		{
+			name:   "valid token",
+			tokens: []string{"valid"},
+			E:      E{requests: 1, refreshes: 0, alive: true},
+		},
+		{
+			name:   "expired token refreshed",
+			tokens: []string{"expired", "valid"},
+			E:      E{requests: 2, refreshes: 1, alive: true},
+		},
+		{
+			name:   "refreshed only once",
+			tokens: []string{"expired", "invalid", "valid"},
+			E:      E{requests: 2, refreshes: 1, kind: ErrUnauthorized},
+		},
+		{
+			name:   "same token is not resent",
+			tokens: []string{"expired", "expired"},
+			E:      E{requests: 1, refreshes: 1, kind: ErrUnauthorized},
+		},
+		{
+			name:   "refresh failed",
+			tokens: []string{"expired"},
+			err:    errors.New("session expired"),
+			E:      E{requests: 1, refreshes: 1, kind: ErrUnauthorized},
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var refreshes int32
+			tokens := TokenFunc(func(ctx context.Context, refresh bool) (string, error) {
+				if !refresh {
+					return tc.tokens[0], nil
+				}
+				n := atomic.AddInt32(&refreshes, 1)
+				if tc.err != nil {
+					return "", tc.err
+				}
+				return tc.tokens[n], nil
+			})
+			s := NewService("", WithTokenSource(tokens))
+			var n int32
+			closeServer := tokenServer(s, &n, "valid")
+			defer closeServer()
+
+			alive, e := s.GetHome()
+			if alive != tc.E.alive {
+				t.Errorf("test %v: expected alive %v got %v", i, tc.E.alive, alive)
+			}
+			if tc.E.kind == nil && e != nil {
+				t.Errorf("test %v: expected no error got %v", i, e)
+			}
+			if tc.E.kind != nil && !errors.Is(e, tc.E.kind) {
+				t.Errorf("test %v: expected error kind %v got %v", i, tc.E.kind, e)
+			}
+			if n != tc.E.requests {
+				t.Errorf("test %v: expected %d requests got %d", i, tc.E.requests, n)
+			}
+			if refreshes != tc.E.refreshes {
+				t.Errorf("test %v: expected %d refreshes got %d", i, tc.E.refreshes, refreshes)
+			}
+		})
+	}
+}
+
+func TestStaticToken(t *testing.T) {
+	s := NewService("ignored", WithTokenSource(StaticToken("expired")))
+	var n int32
+	closeServer := tokenServer(s, &n, "valid")
+	defer closeServer()
+
+	_, e := s.GetHome()
+	if !errors.Is(e, ErrUnauthorized) {
+		t.Errorf("expected error kind %v got %v", ErrUnauthorized, e)
+	}
+	if e != nil && e.Error() != "map[auth:[unauthorized]]" {
+		t.Errorf("expected error %q got %q", "map[auth:[unauthorized]]", e.Error())
+	}
+	if n != 1 {
+		t.Errorf("expected 1 request got %d", n)
+	}
+}
+
+func TestService_ForUser(t *testing.T) {
+	s := NewService("service-token", WithTokenSource(StaticToken("source-token")))
+	tokens := make(chan string, 100)
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		tokens <- r.Header.Get(HeaderUserToken)
+		_, _ = w.Write([]byte(`{"message":"","data":{"alive":true},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	u1 := s.ForUser("user-1")
+	u2 := s.ForUser("user-2")
+	if u1.client != s.client {
+		t.Errorf("expected the view to share the client of the service")
+	}
+	if s.Header.Get(HeaderUserToken) != "service-token" {
+		t.Errorf("expected '%v' got '%v'", "service-token", s.Header.Get(HeaderUserToken))
+	}
+
+	tt := []struct {
+		name  string
+		s     *Service
+		ctx   context.Context
+		token string
+	}{
+		{
+			name:  "service",
+			s:     s,
+			ctx:   context.Background(),
+			token: "source-token",
+		},
+		{
+			name:  "user 1",
+			s:     u1,
+			ctx:   context.Background(),
+			token: "user-1",
+		},
+		{
+			name:  "user 2",
+			s:     u2,
+			ctx:   context.Background(),
+			token: "user-2",
+		},
+		{
+			name:  "context user token",
+			s:     u1,
+			ctx:   ContextWithUserToken(context.Background(), "user-3"),
+			token: "user-3",
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, e := tc.s.GetHomeContext(tc.ctx)
+			if e != nil {
+				t.Errorf("test %v: unexpected error: %v", i, e)
+			}
+			if token := <-tokens; token != tc.token {
+				t.Errorf("test %v: expected token '%v' got '%v'", i, tc.token, token)
+			}
+		})
+	}
+}
+
+func TestService_ForUser_concurrent(t *testing.T) {
+	s := NewService("")
+	srv := mockServer(s, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderUserToken) != r.URL.Query().Get("uuid") {
+			w.WriteHeader(403)
+			_, _ = w.Write([]byte(`{"message":"","data":{},"errors":{"auth":["forbidden"]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":"","data":{"budget":{}},"errors":{}}`))
+	})
+	defer srv.Close()
+
+	errs := make(chan error, 50)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			token := uuid.New()
+			_, e := s.ForUser(token.String()).GetBudget(token)
+			errs <- e
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if e := <-errs; e != nil {
+			t.Errorf("unexpected error: %v", e)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// maxTreeRequests is the maximum number of requests GetBudgetTree has in
+// flight to the budget-micro-service at any one time.
+const maxTreeRequests = 8
+
+// nodeErr is the error of a request made for a single node of the tree.
+type nodeErr struct {
+	node string
+	UUID uuid.UUID
+	e    dutil.Error
+}
+
+// GetBudgetTree retrieves the budget identified by the UUID together with
+// all its groups, sub-groups, items and events. The items of every group and
+// the events of every item are retrieved concurrently, with at most
+// maxTreeRequests requests in flight.
+//
+// If any of the requests fail, the errors are combined into a single error
+// where each error key is prefixed with the node that failed, for example
+// "group:<uuid>:permission".
+func (s *Service) GetBudgetTree(UUID uuid.UUID) (BudgetTree, dutil.Error) {
+	return s.GetBudgetTreeContext(context.Background(), UUID)
+}
+
+// GetBudgetTreeContext is the same as GetBudgetTree but uses the context ctx
+// for the requests made to the budget-micro-service. All the requests share
+// the request ID of the context ctx, or a new request ID, which is also the
+// RequestID of the error returned.
+func (s *Service) GetBudgetTreeContext(ctx context.Context, UUID uuid.UUID) (BudgetTree, dutil.Error) {
+	id := requestID(ctx)
+	ctx = ContextWithRequestID(ctx, id)
+
+	budget, e := s.GetBudgetContext(ctx, UUID)
+	if e != nil {
+		return BudgetTree{}, e
+	}
+	groups, e := s.GetGroupsContext(ctx, UUID)
+	if e != nil {
+		return BudgetTree{}, e
+	}
+	tree := BudgetTree{
+		Budget: budget,
+		Groups: groups,
+	}
+
+	// flatten the groups and sub-groups so the items can be retrieved
+	var xg []*Group
+	var walk func(groups []Group)
+	walk = func(groups []Group) {
+		for i := range groups {
+			xg = append(xg, &groups[i])
+			walk(groups[i].SubGroups)
+		}
+	}
+	walk(tree.Groups)
+
+	errs := make([]*nodeErr, len(xg))
+	fanOut(len(xg), func(i int) {
+		items, e := s.GetItemsContext(ctx, xg[i].UUID)
+		if e != nil {
+			errs[i] = &nodeErr{node: "group", UUID: xg[i].UUID, e: e}
+			return
+		}
+		xg[i].Items = items
+	})
+
+	var xi []*Item
+	for _, g := range xg {
+		for j := range g.Items {
+			xi = append(xi, &g.Items[j])
+		}
+	}
+
+	itemErrs := make([]*nodeErr, len(xi))
+	fanOut(len(xi), func(i int) {
+		events, e := s.GetEventsContext(ctx, xi[i].UUID)
+		if e != nil {
+			itemErrs[i] = &nodeErr{node: "item", UUID: xi[i].UUID, e: e}
+			return
+		}
+		xi[i].Events = events
+	})
+
+	// an aborted context fails all the remaining requests, which is only
+	// reported once
+	if ctx.Err() != nil {
+		return BudgetTree{}, contextErr(id, ctx.Err())
+	}
+	e = combineErrs(append(errs, itemErrs...))
+	if e != nil {
+		return BudgetTree{}, e
+	}
+	return tree, nil
+}
+
+// fanOut calls f for every index in [0, n) using at most maxTreeRequests
+// goroutines.
+func fanOut(n int, f func(i int)) {
+	workers := maxTreeRequests
+	if n < workers {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// combineErrs combines the errors of the nodes of the tree into a single
+// error. The status, kind and request ID of the combined error are those of
+// the first node which failed. A node which failed without any errors is
+// identified by the key "<node>:<uuid>" with the status text of its error.
+// If no node failed combineErrs returns nil.
+func combineErrs(xe []*nodeErr) dutil.Error {
+	var err *Error
+	for _, ne := range xe {
+		if ne == nil {
+			continue
+		}
+		ie := dutil.Inst(ne.e)
+		if err == nil {
+			err = &Error{
+				Err: &dutil.Err{
+					Status: ie.Status,
+					Errors: make(dutil.Errors),
+				},
+			}
+			var be *Error
+			if errors.As(ne.e, &be) {
+				err.Kind = be.Kind
+				err.RequestID = be.RequestID
+			}
+		}
+		if len(ie.Errors) == 0 {
+			text := http.StatusText(ie.Status)
+			if text == "" {
+				text = strconv.Itoa(ie.Status)
+			}
+			k := fmt.Sprintf("%s:%s", ne.node, ne.UUID)
+			err.Errors[k] = append(err.Errors[k], text)
+		}
+		for key, values := range ie.Errors {
+			k := fmt.Sprintf("%s:%s:%s", ne.node, ne.UUID, key)
+			err.Errors[k] = append(err.Errors[k], values...)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,324 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// treeServer creates a mock budget-micro-service which responds to the
+// requests based on the request URI, so that the requests may arrive in
+// any order.
+func treeServer(t *testing.T, responses map[string]string, failures map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body, ok := failures[r.RequestURI]; ok {
+			w.WriteHeader(403)
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		body, ok := responses[r.RequestURI]
+		if !ok {
+			t.Errorf("unexpected request URI '%s'", r.RequestURI)
+			w.WriteHeader(404)
+			_, _ = w.Write([]byte(`{"message":"","data":{},"errors":{"uri":["not found"]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+var treeResponses = map[string]string{
+	"/budget/-?uuid=f5fca9d0-e308-4ff2-be4e-aff22a4c2a78": `{
+		"message":"budget found successfully",
+		"data":{"budget":{"uuid":"f5fca9d0-e308-4ff2-be4e-aff22a4c2a78","name":"test budget","active":true}},
+		"errors":{}
+	}`,
+	"/budget/-/group?uuid=f5fca9d0-e308-4ff2-be4e-aff22a4c2a78": `{
+		"message":"groups found successfully",
+		"data":{"groups":[
+			{
+				"uuid":"52f2c725-2cdc-401a-abdd-66db5fd06789","name":"income","active":true,
+				"sub_groups":[{"uuid":"b8448a78-6417-4fe2-849c-024622bc6106","name":"base salary","active":true}]
+			},
+			{"uuid":"6be3df72-da3d-4a8c-bef6-d0b57120b80a","name":"expenses","active":true}
+		]},
+		"errors":{}
+	}`,
+	"/budget/group/-/item?uuid=52f2c725-2cdc-401a-abdd-66db5fd06789": `{
+		"message":"items found successfully","data":{"items":[]},"errors":{}
+	}`,
+	"/budget/group/-/item?uuid=b8448a78-6417-4fe2-849c-024622bc6106": `{
+		"message":"items found successfully",
+		"data":{"items":[{"uuid":"0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d","name":"salary","active":true}]},
+		"errors":{}
+	}`,
+	"/budget/group/-/item?uuid=6be3df72-da3d-4a8c-bef6-d0b57120b80a": `{
+		"message":"items found successfully",
+		"data":{"items":[
+			{"uuid":"9db1590a-9f77-47af-baa6-0a786095e510","name":"rent","active":true},
+			{"uuid":"08aa5301-2285-4f2f-b930-677683468e0f","name":"groceries","active":true}
+		]},
+		"errors":{}
+	}`,
+	"/budget/group/item/-/event?uuid=0e2a3a7b-87d1-4dbb-9e8e-1d6f3a2b1c4d": `{
+		"message":"events found successfully",
+		"data":{"events":[{"uuid":"d89b8fc2-9e64-4706-a4db-17ffbe6d16a9","name":"salary","credit":true,"amount":1000}]},
+		"errors":{}
+	}`,
+	"/budget/group/item/-/event?uuid=9db1590a-9f77-47af-baa6-0a786095e510": `{
+		"message":"events found successfully",
+		"data":{"events":[{"uuid":"6aa6d86e-8bcc-4706-a2d1-82bbfc7e7c97","name":"rent","debit":true,"amount":500}]},
+		"errors":{}
+	}`,
+	"/budget/group/item/-/event?uuid=08aa5301-2285-4f2f-b930-677683468e0f": `{
+		"message":"events found successfully",
+		"data":{"events":[
+			{"uuid":"603f9589-ecb3-437a-9406-1652eb5a38eb","name":"week one","debit":true,"amount":50},
+			{"uuid":"b86768ee-69de-4fb2-81eb-ab96d14e37ae","name":"week two","debit":true,"amount":60}
+		]},
+		"errors":{}
+	}`,
+}
+
+func TestService_GetBudgetTree(t *testing.T) {
+	type E struct {
+		e      dutil.Error
+		groups int
+	}
+	tt := []struct {
+		name     string
+		failures map[string]string
+		E        E
+	}{
+		{
+			name: "403 Budget",
+			failures: map[string]string{
+				"/budget/-?uuid=f5fca9d0-e308-4ff2-be4e-aff22a4c2a78": `{"message":"","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"permission": {"Please ensure you have permission"},
+					},
+				},
+			},
+		},
+		{
+			name: "403 Items and Events",
+			failures: map[string]string{
+				"/budget/group/-/item?uuid=52f2c725-2cdc-401a-abdd-66db5fd06789":       `{"message":"","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+				"/budget/group/item/-/event?uuid=08aa5301-2285-4f2f-b930-677683468e0f": `{"message":"","data":{},"errors":{"auth":["token expired"]}}`,
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"group:52f2c725-2cdc-401a-abdd-66db5fd06789:permission": {"Please ensure you have permission"},
+						"item:08aa5301-2285-4f2f-b930-677683468e0f:auth":        {"token expired"},
+					},
+				},
+			},
+		},
+		{
+			name: "403 Items without errors",
+			failures: map[string]string{
+				"/budget/group/-/item?uuid=6be3df72-da3d-4a8c-bef6-d0b57120b80a": `{"message":"","data":{},"errors":{}}`,
+			},
+			E: E{
+				e: &dutil.Err{
+					Status: 403,
+					Errors: map[string][]string{
+						"group:6be3df72-da3d-4a8c-bef6-d0b57120b80a": {"Forbidden"},
+					},
+				},
+			},
+		},
+		{
+			name:     "200 Successful",
+			failures: map[string]string{},
+			E: E{
+				e:      nil,
+				groups: 2,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := treeServer(t, treeResponses, tc.failures)
+			defer srv.Close()
+
+			s := NewService("")
+			u, _ := url.Parse(srv.URL)
+			s.SetURL(u.Scheme, u.Host)
+
+			tree, e := s.GetBudgetTree(uuid.MustParse("f5fca9d0-e308-4ff2-be4e-aff22a4c2a78"))
+			if tc.E.e != nil {
+				if e == nil || tc.E.e.Error() != e.Error() {
+					t.Errorf("expected error '%v' got '%v'", tc.E.e, e)
+				}
+				if e != nil && dutil.Inst(e).Status != dutil.Inst(tc.E.e).Status {
+					t.Errorf("expected status %d got %d", dutil.Inst(tc.E.e).Status, dutil.Inst(e).Status)
+				}
+				if e != nil && !errors.Is(e, ErrForbidden) {
+					t.Errorf("expected error of kind '%v' got '%v'", ErrForbidden, e)
+				}
+			} else if e != nil {
+				t.Errorf("unexpected error: %s", e.Error())
+			}
+			if len(tree.Groups) != tc.E.groups {
+				t.Errorf("expected %d groups got %d", tc.E.groups, len(tree.Groups))
+			}
+		})
+	}
+}
+
+func TestService_GetBudgetTree_populated(t *testing.T) {
+	srv := treeServer(t, treeResponses, nil)
+	defer srv.Close()
+
+	s := NewService("")
+	u, _ := url.Parse(srv.URL)
+	s.SetURL(u.Scheme, u.Host)
+
+	tree, e := s.GetBudgetTree(uuid.MustParse("f5fca9d0-e308-4ff2-be4e-aff22a4c2a78"))
+	if e != nil {
+		t.Fatalf("unexpected error: %s", e.Error())
+	}
+	if tree.Budget.Name != "test budget" {
+		t.Errorf("expected budget name '%s' got '%s'", "test budget", tree.Budget.Name)
+	}
+
+	income := tree.Groups[0]
+	if len(income.Items) != 0 {
+		t.Errorf("expected income items %d got %d", 0, len(income.Items))
+	}
+	salary := income.SubGroups[0]
+	if len(salary.Items) != 1 {
+		t.Fatalf("expected salary items %d got %d", 1, len(salary.Items))
+	}
+	if len(salary.Items[0].Events) != 1 || salary.Items[0].Events[0].Amount != 1000 {
+		t.Errorf("expected salary events '%v' got '%v'", "[salary 1000]", salary.Items[0].Events)
+	}
+
+	expenses := tree.Groups[1]
+	if len(expenses.Items) != 2 {
+		t.Fatalf("expected expenses items %d got %d", 2, len(expenses.Items))
+	}
+	if len(expenses.Items[0].Events) != 1 {
+		t.Errorf("expected rent events %d got %d", 1, len(expenses.Items[0].Events))
+	}
+	if len(expenses.Items[1].Events) != 2 {
+		t.Errorf("expected groceries events %d got %d", 2, len(expenses.Items[1].Events))
+	}
+}
+
+func TestService_GetBudgetTreeContext_cancelled(t *testing.T) {
+	srv := treeServer(t, treeResponses, nil)
+	defer srv.Close()
+
+	s := NewService("")
+	u, _ := url.Parse(srv.URL)
+	s.SetURL(u.Scheme, u.Host)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, e := s.GetBudgetTreeContext(ctx, uuid.MustParse("f5fca9d0-e308-4ff2-be4e-aff22a4c2a78"))
+	if e == nil {
+		t.Fatalf("expected error got nil")
+	}
+	if dutil.Inst(e).Status != StatusCanceled {
+		t.Errorf("expected status %d got %d", StatusCanceled, dutil.Inst(e).Status)
+	}
+}
+
+func TestService_GetBudgetTreeContext_requestID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ids := make(chan string, 20)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids <- r.Header.Get(HeaderRequestID)
+		// abort the tree while the items are retrieved
+		if r.URL.Path == "/budget/group/-/item" {
+			cancel()
+		}
+		_, _ = w.Write([]byte(treeResponses[r.RequestURI]))
+	}))
+	defer srv.Close()
+
+	s := NewService("")
+	u, _ := url.Parse(srv.URL)
+	s.SetURL(u.Scheme, u.Host)
+
+	_, e := s.GetBudgetTreeContext(ctx, uuid.MustParse("f5fca9d0-e308-4ff2-be4e-aff22a4c2a78"))
+	var te *Error
+	if !errors.As(e, &te) {
+		t.Fatalf("expected an *Error got %v", e)
+	}
+	if te.RequestID == "" {
+		t.Errorf("expected a request id got '%v'", te.RequestID)
+	}
+	close(ids)
+	for id := range ids {
+		if id != te.RequestID {
+			t.Errorf("expected request id '%v' got '%v'", te.RequestID, id)
+		}
+	}
+}
+
+func TestService_GetBudgetTree_maxTreeRequests(t *testing.T) {
+	// a budget with more groups than the requests in flight at once
+	var groups []string
+	for i := 0; i < 3*maxTreeRequests; i++ {
+		groups = append(groups, `{"uuid":"`+uuid.New().String()+`","name":"group","active":true}`)
+	}
+	var inFlight, max int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		switch r.URL.Path {
+		case "/budget/-":
+			_, _ = w.Write([]byte(`{"message":"","data":{"budget":{"name":"test budget"}},"errors":{}}`))
+		case "/budget/-/group":
+			_, _ = w.Write([]byte(`{"message":"","data":{"groups":[` + strings.Join(groups, ",") + `]},"errors":{}}`))
+		default:
+			time.Sleep(10 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"message":"","data":{"items":[]},"errors":{}}`))
+		}
+	}))
+	defer srv.Close()
+
+	s := NewService("")
+	u, _ := url.Parse(srv.URL)
+	s.SetURL(u.Scheme, u.Host)
+
+	tree, e := s.GetBudgetTree(uuid.New())
+	if e != nil {
+		t.Fatalf("unexpected error: %v", e)
+	}
+	if len(tree.Groups) != len(groups) {
+		t.Errorf("expected %d groups got %d", len(groups), len(tree.Groups))
+	}
+	if max > maxTreeRequests {
+		t.Errorf("expected at most %d requests in flight got %d", maxTreeRequests, max)
+	}
+	if max < 2 {
+		t.Errorf("expected the items to be retrieved concurrently got %d requests in flight", max)
+	}
+}